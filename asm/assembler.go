@@ -79,7 +79,11 @@ func AssembleLine(labels prog.SymbolTable, line string, address uint) (prog.Inst
 
 	opcode, ok := prog.ParseOpcode(mnemonic)
 	if !ok {
-		return nil, fmt.Errorf("'%s' is not a legal mnemonic", mnemonic)
+		err := fmt.Errorf("'%s' is not a legal mnemonic", mnemonic)
+		if fix, ok := prog.SuggestMnemonic(mnemonic, operands); ok {
+			err = &prog.FixableError{Err: err, Suggestions: []prog.Suggestion{fix}}
+		}
+		return nil, err
 	}
 
 	inst := prog.NewInstruction(opcode)
@@ -89,6 +93,21 @@ func AssembleLine(labels prog.SymbolTable, line string, address uint) (prog.Inst
 	return inst, inst.Error()
 }
 
+// An error found when assembling a particular line of source code
+type LineError struct {
+	Line   int    // line number, starting at 1
+	Source string // source code line with surrounding whitespace removed
+	Err    error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: unable to assemble '%s' because %v", e.Line, e.Source, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
 // Assembler reads assembly code from reader and writes machine code to writer
 func Assemble(reader io.ReadSeeker) (*prog.Program, error) {
 	labels := prog.ReadSymTable(reader)
@@ -107,7 +126,7 @@ func Assemble(reader io.ReadSeeker) (*prog.Program, error) {
 		line := scanner.Text()
 		instruction, err := AssembleLine(program.Labels, line, addr)
 		if err != nil {
-			return nil, fmt.Errorf("line %d: unable to assemble '%s' because %w", lineNo, strings.TrimSpace(line), err)
+			return nil, &LineError{lineNo, strings.TrimSpace(line), err}
 		}
 		if instruction != nil {
 			program.Add(instruction)
@@ -121,6 +140,32 @@ func Assemble(reader io.ReadSeeker) (*prog.Program, error) {
 	return &program, nil
 }
 
+// Check assembles all of the code read from reader and returns an error for
+// every line which could not be assembled, rather than stopping at the first one
+func Check(reader io.ReadSeeker) []*LineError {
+	labels := prog.ReadSymTable(reader)
+	labels.AddIOLabels()
+	reader.Seek(0, io.SeekStart)
+
+	errors := make([]*LineError, 0)
+	scanner := bufio.NewScanner(reader)
+
+	var addr uint = 0
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if _, code, _ := prog.SplitLine(line); code == "" {
+			continue
+		}
+
+		// keep addresses in sync with symbol table even for erroneous lines
+		if _, err := AssembleLine(labels, line, addr); err != nil {
+			errors = append(errors, &LineError{lineNo, strings.TrimSpace(line), err})
+		}
+		addr++
+	}
+	return errors
+}
+
 // AssembleFile reads assembly code from file at path filepath and write machinecode to writer
 func AssembleFile(filepath string) (*prog.Program, error) {
 	file, err := os.Open(filepath)
@@ -1,6 +1,7 @@
 package asm
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -202,3 +203,29 @@ func TestImmediateRange(t *testing.T) {
 	}
 
 }
+
+func TestOperandErrors(t *testing.T) {
+	labels := prog.SymbolTable{"loop": 0}
+
+	// misspelled label is reported instead of being left out
+	for _, line := range []string{"BRA lop", "ADD x1, x2, y3", "LODI x1, ten"} {
+		_, err := AssembleLine(labels, line, 0)
+		if err == nil || !strings.Contains(err.Error(), "is not a register, number or known label") {
+			t.Errorf("expected unknown operand in '%s' to be an error, got %v", line, err)
+		}
+	}
+	if _, err := AssembleLine(labels, "BRA loop", 2); err != nil {
+		t.Errorf("failed to assemble branch to known label because %v", err)
+	}
+
+	// operands after a constant out of range are still parsed, so a fix naming
+	// the register can be suggested
+	_, err := AssembleLine(labels, "LODI x1, 150", 0)
+	var fixable *prog.FixableError
+	if !errors.As(err, &fixable) || len(fixable.Suggestions) != 1 || fixable.Suggestions[0].Lines[0] != "LODI x1, 49" {
+		t.Errorf("expected fix splitting 150 into parts loaded into x1, got %v", err)
+	}
+	if _, err := AssembleLine(labels, "LODI x1, 500", 0); err == nil || !strings.Contains(err.Error(), "outside valid range") {
+		t.Errorf("expected constant 500 to be outside valid range, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package asm
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ordovician/calcutron/prog"
+)
+
+// Limit on rounds of fixing, since fixing one line can cause errors in other
+// lines. E.g. inserting instructions can put a label out of reach of a branch
+const maxFixRounds = 10
+
+// A fix which was applied to a line of source code
+type Fix struct {
+	Line    int      // line number of fixed line at the time of fixing, starting at 1
+	Message string   // description of what was done
+	Old     string   // line before fix
+	New     []string // lines replacing Old
+}
+
+// Suggestions for fixing err, if there are any
+func Suggestions(err error) []prog.Suggestion {
+	var fixable *prog.FixableError
+	if errors.As(err, &fixable) {
+		return fixable.Suggestions
+	}
+	return nil
+}
+
+// ApplySuggestion returns the lines replacing line when applying fix. Label and
+// comment of line is kept on the first line and the following lines get the
+// same indentation as the instruction being replaced.
+func ApplySuggestion(line string, fix prog.Suggestion) []string {
+	_, code, _ := prog.SplitLine(line)
+	if code == "" || len(fix.Lines) == 0 {
+		return []string{line}
+	}
+
+	i := strings.Index(line, code)
+	lines := []string{line[:i] + fix.Lines[0] + line[i+len(code):]}
+
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	if label, _, _ := prog.SplitLine(line); label != "" || indent == "" {
+		indent = "    "
+	}
+	for _, extra := range fix.Lines[1:] {
+		lines = append(lines, indent+extra)
+	}
+	return lines
+}
+
+// FixSource applies the first suggested fix of every line which fails to
+// assemble, until the source assembles or no more fixes can be made. Returns
+// the fixed source code, the fixes made and the errors which remain.
+func FixSource(src string) (string, []Fix, []*LineError) {
+	lines := strings.Split(src, "\n")
+	fixes := make([]Fix, 0)
+
+	for round := 0; round < maxFixRounds; round++ {
+		errs := Check(strings.NewReader(strings.Join(lines, "\n")))
+
+		// apply fixes bottom up so line numbers of earlier errors remain valid
+		roundFixes := make([]Fix, 0)
+		for i := len(errs) - 1; i >= 0; i-- {
+			err := errs[i]
+			suggestions := Suggestions(err)
+			if len(suggestions) == 0 {
+				continue
+			}
+
+			old := lines[err.Line-1]
+			replacement := ApplySuggestion(old, suggestions[0])
+			roundFixes = append(roundFixes, Fix{
+				Line:    err.Line,
+				Message: suggestions[0].Message,
+				Old:     old,
+				New:     replacement,
+			})
+
+			updated := make([]string, 0, len(lines)+len(replacement))
+			updated = append(updated, lines[:err.Line-1]...)
+			updated = append(updated, replacement...)
+			lines = append(updated, lines[err.Line:]...)
+		}
+
+		// report fixes in the order they appear in the source
+		for i := len(roundFixes) - 1; i >= 0; i-- {
+			fixes = append(fixes, roundFixes[i])
+		}
+		if len(roundFixes) == 0 {
+			return strings.Join(lines, "\n"), fixes, errs
+		}
+	}
+
+	fixedSrc := strings.Join(lines, "\n")
+	return fixedSrc, fixes, Check(strings.NewReader(fixedSrc))
+}
@@ -0,0 +1,51 @@
+package asm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFixSource(t *testing.T) {
+	src := `start:
+    LAOD x1, x0, 0
+    LODI x2, 90    // too big
+    ADDI x3, -70
+    BGT  x1, x2, start
+    BRA  start
+`
+	fixed, fixes, errs := FixSource(src)
+	if len(errs) != 0 {
+		t.Fatalf("expected fixed source to assemble, got %v", errs)
+	}
+	if len(fixes) != 4 {
+		t.Errorf("expected 4 fixes got %d", len(fixes))
+	}
+	if !strings.Contains(fixed, "    LODI x2, 49    // too big\n    ADDI x2, 41") {
+		t.Errorf("expected LODI to be split keeping comment, got\n%s", fixed)
+	}
+	if !strings.Contains(fixed, "LOAD x1, x0, 0") {
+		t.Errorf("expected LAOD to be corrected to LOAD, got\n%s", fixed)
+	}
+}
+
+func TestFixLocalLabel(t *testing.T) {
+	src := `base:
+.first: DAT 1
+    INC  x1
+    INC  x1
+    INC  x1
+    INC  x1
+    INC  x1
+    INC  x1
+    INC  x1
+    INC  x1
+    BEQ  x1, x0, .first
+`
+	fixed, fixes, errs := FixSource(src)
+	if len(fixes) != 0 || len(errs) != 1 {
+		t.Errorf("expected branch to local label to be left unfixed, got %d fixes and errors %v", len(fixes), errs)
+	}
+	if strings.Contains(fixed, "JMP") {
+		t.Errorf("expected no JMP to offset of local label, got\n%s", fixed)
+	}
+}
@@ -0,0 +1,233 @@
+// Package cache simulates a data cache between the instructions of a
+// Calcutron-33 program and its memory, to show how the order in which a
+// program accesses memory affects its speed.
+//
+// Memory is divided into lines of a few words, and the cache keeps copies of
+// recently used lines. A line can only be placed in one set of the cache,
+// decided by its address, and each set holds as many lines as the cache has
+// ways. When a set is full, the least recently used line is evicted. Programs
+// walking through an array get hits on the words following the first one in
+// each line, while programs hopping around get few hits.
+//
+// With write-back, writes only go to the cache and a changed line is written
+// to memory when evicted. With write-through, every write goes to memory,
+// and writes which miss do not bring their line into the cache.
+//
+// The cache only keeps track of which lines it holds, never their contents,
+// so it cannot change what a program does, only how many cycles it takes.
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Config struct {
+	Size        int  // number of words in cache
+	LineSize    int  // number of words in each line
+	Ways        int  // number of lines in each set, 1 for a direct mapped cache
+	WriteBack   bool // write changed lines to memory when evicted rather than on every write
+	MissPenalty uint // cycles taken to read or write a line, or a single word when writing through
+}
+
+var DefaultConfig = Config{
+	Size:        16,
+	LineSize:    4,
+	Ways:        1,
+	WriteBack:   true,
+	MissPenalty: 10,
+}
+
+// Parse configuration given on command line as a comma separated list such as
+// size=32,line=4,ways=2,write-through,penalty=20. Settings left out keep their
+// value from DefaultConfig, so "default" gives the default cache
+func ParseConfig(spec string) (Config, error) {
+	config := DefaultConfig
+	for _, setting := range strings.Split(spec, ",") {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(setting), "=")
+		if !hasValue {
+			switch name {
+			case "default":
+			case "write-back":
+				config.WriteBack = true
+			case "write-through":
+				config.WriteBack = false
+			default:
+				return config, fmt.Errorf("unknown cache setting '%s'", name)
+			}
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return config, fmt.Errorf("cache setting %s must be a positive number, not '%s'", name, value)
+		}
+		switch name {
+		case "size":
+			config.Size = n
+		case "line":
+			config.LineSize = n
+		case "ways":
+			config.Ways = n
+		case "penalty":
+			config.MissPenalty = uint(n)
+		default:
+			return config, fmt.Errorf("unknown cache setting '%s'", name)
+		}
+	}
+	return config, config.validate()
+}
+
+func (config Config) validate() error {
+	if config.Size <= 0 || config.LineSize <= 0 || config.Ways <= 0 {
+		return fmt.Errorf("cache size, line size and ways must be positive")
+	}
+	if config.Size%(config.LineSize*config.Ways) != 0 {
+		return fmt.Errorf("cache of %d words cannot be divided into sets of %d lines of %d words",
+			config.Size, config.Ways, config.LineSize)
+	}
+	return nil
+}
+
+// Number of sets lines are divided into
+func (config Config) Sets() int {
+	return config.Size / (config.LineSize * config.Ways)
+}
+
+func (config Config) String() string {
+	mapping := fmt.Sprintf("%d-way set associative", config.Ways)
+	if config.Ways == 1 {
+		mapping = "direct mapped"
+	} else if config.Sets() == 1 {
+		mapping = "fully associative"
+	}
+	policy := "write-through"
+	if config.WriteBack {
+		policy = "write-back"
+	}
+	return fmt.Sprintf("%d words in lines of %d, %s, %s, %d cycles miss penalty",
+		config.Size, config.LineSize, mapping, policy, config.MissPenalty)
+}
+
+// Counts of accesses made to cache
+type Stats struct {
+	Reads      uint
+	Writes     uint
+	Hits       uint
+	Misses     uint
+	Evictions  uint // lines pushed out to make room for another
+	WriteBacks uint // evicted lines written back to memory since they were changed
+}
+
+// Percentage of accesses which hit
+func (stats *Stats) HitRate() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return 100 * float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+type line struct {
+	valid    bool
+	tag      uint
+	dirty    bool
+	lastUsed uint
+}
+
+// Cache implements sim.DataCache
+type Cache struct {
+	Config
+	Total         Stats
+	ByInstruction map[uint]*Stats // stats of accesses made by instruction at each address
+	ByAddress     map[uint]*Stats // stats of accesses to each memory address
+
+	sets  [][]line
+	clock uint // counts accesses, to find least recently used line
+}
+
+func New(config Config) (*Cache, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	sets := make([][]line, config.Sets())
+	for i := range sets {
+		sets[i] = make([]line, config.Ways)
+	}
+	return &Cache{
+		Config:        config,
+		ByInstruction: make(map[uint]*Stats),
+		ByAddress:     make(map[uint]*Stats),
+		sets:          sets,
+	}, nil
+}
+
+func stats(byAddress map[uint]*Stats, addr uint) *Stats {
+	s := byAddress[addr]
+	if s == nil {
+		s = &Stats{}
+		byAddress[addr] = s
+	}
+	return s
+}
+
+// Access word at address by instruction at pc, returning cycles spent waiting for memory
+func (cache *Cache) Access(pc, address uint, write bool) uint {
+	cache.clock++
+	lineAddr := address / uint(cache.LineSize)
+	set := cache.sets[lineAddr%uint(len(cache.sets))]
+	tag := lineAddr / uint(len(cache.sets))
+
+	all := []*Stats{&cache.Total, stats(cache.ByInstruction, pc), stats(cache.ByAddress, address)}
+	count := func(update func(s *Stats)) {
+		for _, s := range all {
+			update(s)
+		}
+	}
+	if write {
+		count(func(s *Stats) { s.Writes++ })
+	} else {
+		count(func(s *Stats) { s.Reads++ })
+	}
+
+	var cycles uint
+	if write && !cache.WriteBack {
+		cycles += cache.MissPenalty
+	}
+
+	for i := range set {
+		if set[i].valid && set[i].tag == tag {
+			count(func(s *Stats) { s.Hits++ })
+			set[i].lastUsed = cache.clock
+			set[i].dirty = set[i].dirty || write && cache.WriteBack
+			return cycles
+		}
+	}
+	count(func(s *Stats) { s.Misses++ })
+
+	// writing through without a line in the cache goes straight to memory
+	if write && !cache.WriteBack {
+		return cycles
+	}
+
+	victim := &set[0]
+	for i := range set {
+		if !set[i].valid {
+			victim = &set[i]
+			break
+		}
+		if set[i].lastUsed < victim.lastUsed {
+			victim = &set[i]
+		}
+	}
+	if victim.valid {
+		count(func(s *Stats) { s.Evictions++ })
+		if victim.dirty {
+			count(func(s *Stats) { s.WriteBacks++ })
+			cycles += cache.MissPenalty
+		}
+	}
+
+	*victim = line{valid: true, tag: tag, dirty: write, lastUsed: cache.clock}
+	return cycles + cache.MissPenalty
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/ordovician/calcutron/sim"
+	"golang.org/x/exp/slices"
+)
+
+type access struct {
+	address uint
+	write   bool
+	cycles  uint // cycles expected to be spent waiting on memory
+}
+
+func accessAll(t *testing.T, cache *Cache, accesses []access) {
+	t.Helper()
+	for i, acc := range accesses {
+		if cycles := cache.Access(0, acc.address, acc.write); cycles != acc.cycles {
+			t.Errorf("expected access %d to address %d to take %d cycles, got %d", i, acc.address, acc.cycles, cycles)
+		}
+	}
+}
+
+func TestAssociativity(t *testing.T) {
+	// addresses 0 and 4 are in lines mapped to the same set of a direct mapped cache
+	direct, _ := New(Config{Size: 4, LineSize: 2, Ways: 1, WriteBack: true, MissPenalty: 10})
+	accessAll(t, direct, []access{{0, false, 10}, {4, false, 10}, {1, false, 10}, {5, false, 10}})
+	if expected := (Stats{Reads: 4, Misses: 4, Evictions: 3}); direct.Total != expected {
+		t.Errorf("expected %+v, got %+v", expected, direct.Total)
+	}
+
+	// with two ways both lines fit, and the least recently used one is evicted
+	twoWay, _ := New(Config{Size: 4, LineSize: 2, Ways: 2, WriteBack: true, MissPenalty: 10})
+	accessAll(t, twoWay, []access{{0, false, 10}, {4, false, 10}, {1, false, 0}, {5, false, 0}, {2, false, 10}, {4, false, 0}, {0, false, 10}})
+	if expected := (Stats{Reads: 7, Hits: 3, Misses: 4, Evictions: 2}); twoWay.Total != expected {
+		t.Errorf("expected %+v, got %+v", expected, twoWay.Total)
+	}
+}
+
+func TestWritePolicies(t *testing.T) {
+	config := Config{Size: 2, LineSize: 2, Ways: 1, WriteBack: true, MissPenalty: 10}
+
+	// changed line is written to memory when evicted
+	writeBack, _ := New(config)
+	accessAll(t, writeBack, []access{{0, true, 10}, {1, true, 0}, {4, false, 20}, {0, false, 10}})
+	if expected := (Stats{Reads: 2, Writes: 2, Hits: 1, Misses: 3, Evictions: 2, WriteBacks: 1}); writeBack.Total != expected {
+		t.Errorf("expected %+v, got %+v", expected, writeBack.Total)
+	}
+
+	// every write goes to memory, and writes which miss do not fill the cache
+	config.WriteBack = false
+	writeThrough, _ := New(config)
+	accessAll(t, writeThrough, []access{{0, true, 10}, {1, true, 10}, {4, false, 10}, {5, true, 10}, {0, false, 10}})
+	if expected := (Stats{Reads: 2, Writes: 3, Hits: 1, Misses: 4, Evictions: 1}); writeThrough.Total != expected {
+		t.Errorf("expected %+v, got %+v", expected, writeThrough.Total)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig("size=32, ways=2,write-through,penalty=20")
+	expected := Config{Size: 32, LineSize: 4, Ways: 2, WriteBack: false, MissPenalty: 20}
+	if err != nil || config != expected {
+		t.Errorf("expected %+v, got %+v and error %v", expected, config, err)
+	}
+	if config, err = ParseConfig("default"); err != nil || config != DefaultConfig {
+		t.Errorf("expected default config, got %+v and error %v", config, err)
+	}
+
+	for _, spec := range []string{"size=10", "ways=0", "line=two", "colour=red", "write-around"} {
+		if _, err := ParseConfig(spec); err == nil {
+			t.Errorf("expected '%s' to be an error", spec)
+		}
+	}
+}
+
+func TestSimulatedCache(t *testing.T) {
+	run := func(cache *Cache) *sim.Computer {
+		comp, err := sim.NewComputerFile("../examples/reverser.ct33")
+		if err != nil {
+			t.Fatalf("failed to load program: %v", err)
+		}
+		comp.SetInputs([]uint{3, 7, 1, 5})
+		if cache != nil {
+			comp.Cache = cache
+		}
+		comp.Run(100)
+		return comp
+	}
+
+	plain := run(nil)
+	cache, _ := New(DefaultConfig)
+	cached := run(cache)
+
+	if !slices.Equal(plain.Outputs(), cached.Outputs()) {
+		t.Errorf("expected cache not to change outputs %v, got %v", plain.Outputs(), cached.Outputs())
+	}
+
+	// array at 15 to 17 spans two lines, which are only missed when first written
+	if expected := (Stats{Reads: 3, Writes: 3, Hits: 4, Misses: 2}); cache.Total != expected {
+		t.Errorf("expected %+v, got %+v", expected, cache.Total)
+	}
+	if stats := cache.ByInstruction[10]; stats == nil || stats.Reads != 3 || stats.Hits != 3 {
+		t.Errorf("expected LOAD at 10 to hit three times, got %+v", stats)
+	}
+	if extra := cached.Cycles() - plain.Cycles(); extra != 2*DefaultConfig.MissPenalty {
+		t.Errorf("expected two misses to add %d cycles, got %d", 2*DefaultConfig.MissPenalty, extra)
+	}
+}
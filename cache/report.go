@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ordovician/calcutron/disasm"
+	"github.com/ordovician/calcutron/sim"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// Memory from a label up to the next one, such as an array of data
+type region struct {
+	name  string
+	start uint
+	Stats
+}
+
+func (stats *Stats) add(other *Stats) {
+	stats.Reads += other.Reads
+	stats.Writes += other.Writes
+	stats.Hits += other.Hits
+	stats.Misses += other.Misses
+	stats.Evictions += other.Evictions
+	stats.WriteBacks += other.WriteBacks
+}
+
+func writeStats(writer io.Writer, stats *Stats) {
+	fmt.Fprintf(writer, "%5d  %6d  %4d  %6d  %9d  %11d  %7.1f%%",
+		stats.Reads, stats.Writes, stats.Hits, stats.Misses, stats.Evictions, stats.WriteBacks, stats.HitRate())
+}
+
+const statsHeader = "Reads  Writes  Hits  Misses  Evictions  Write-backs  Hit rate"
+
+// Write totals for the cache, followed by accesses made by every instruction
+// reading or writing memory, and accesses to each region of memory starting
+// at a label of the program loaded into comp
+func (cache *Cache) WriteReport(writer io.Writer, comp *sim.Computer) {
+	program, _ := disasm.DisassembleMemoryWithOptions(comp.ProgramSlice(), &disasm.Options{
+		Entry:   comp.Entry(),
+		Symbols: comp.Labels(),
+	})
+	labels := make(map[uint]string)
+	for label, addr := range program.Labels {
+		if other, ok := labels[addr]; !ok || label < other {
+			labels[addr] = label
+		}
+	}
+
+	fmt.Fprintf(writer, "Data cache: %v\n", cache.Config)
+	fmt.Fprintf(writer, "%s\n", statsHeader)
+	writeStats(writer, &cache.Total)
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer)
+
+	fmt.Fprintf(writer, "%s  Addr  Source\n", statsHeader)
+	pcs := maps.Keys(cache.ByInstruction)
+	slices.Sort(pcs)
+	for _, pc := range pcs {
+		writeStats(writer, cache.ByInstruction[pc])
+		source := ""
+		if pc < uint(len(program.Instructions)) {
+			source = program.Instructions[pc].SourceCode()
+		}
+		label := ""
+		if name, ok := labels[pc]; ok {
+			label = name + ":"
+		}
+		fmt.Fprintf(writer, "  %02d    %-12s %s\n", pc, label, source)
+	}
+	fmt.Fprintln(writer)
+
+	// each data address belongs to the closest label at or before it
+	starts := maps.Keys(labels)
+	slices.Sort(starts)
+	var regions []*region
+	byStart := make(map[uint]*region)
+	addrs := maps.Keys(cache.ByAddress)
+	slices.Sort(addrs)
+	for _, addr := range addrs {
+		i, found := slices.BinarySearch(starts, addr)
+		if !found {
+			i--
+		}
+		var start uint
+		name := "(unlabeled)"
+		if i >= 0 {
+			start = starts[i]
+			name = labels[start]
+		}
+		reg := byStart[start]
+		if reg == nil {
+			reg = &region{name: name, start: start}
+			byStart[start] = reg
+			regions = append(regions, reg)
+		}
+		reg.add(cache.ByAddress[addr])
+	}
+
+	fmt.Fprintf(writer, "%s  Region\n", statsHeader)
+	for _, reg := range regions {
+		writeStats(writer, &reg.Stats)
+		fmt.Fprintf(writer, "  %s (%02d)\n", reg.name, reg.start)
+	}
+}
@@ -1,20 +1,35 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path"
+	"strconv"
 	"strings"
 	"sync"
 
+	"github.com/chzyer/readline"
 	"github.com/fatih/color"
 	"github.com/ordovician/calcutron/asm"
+	"github.com/ordovician/calcutron/cache"
 	"github.com/ordovician/calcutron/dbg"
+	"github.com/ordovician/calcutron/decomp"
+	"github.com/ordovician/calcutron/device"
 	"github.com/ordovician/calcutron/disasm"
+	"github.com/ordovician/calcutron/format"
+	"github.com/ordovician/calcutron/listing"
+	"github.com/ordovician/calcutron/lsp"
+	"github.com/ordovician/calcutron/pipeline"
+	"github.com/ordovician/calcutron/predict"
+	"github.com/ordovician/calcutron/profile"
 	"github.com/ordovician/calcutron/prog"
 	"github.com/ordovician/calcutron/sim"
-
-	// "github.com/ordovician/calcutron/utils"
+	"github.com/ordovician/calcutron/utils"
 	"github.com/urfave/cli/v2"
 )
 
@@ -23,31 +38,147 @@ var printOptions prog.PrintOptions
 func assemble(ctx *cli.Context) error {
 	errorColor := color.New(color.FgRed)
 	filepath := ctx.Args().First()
+
+	if name := ctx.String("format"); name != "text" {
+		return writeListing(filepath, name)
+	}
+
 	program, err := asm.AssembleFile(filepath)
+	if err == nil && ctx.IsSet("entry") {
+		program.Entry, err = entryAddress(program, ctx.String("entry"))
+	}
 	if err != nil {
 		errorColor.Fprintf(os.Stderr, "Error: ")
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		return nil
 	}
 
+	if ctx.Bool("header") {
+		program.Name = strings.TrimSuffix(path.Base(filepath), path.Ext(filepath))
+		return program.WriteMachineFile(os.Stdout)
+	}
 	program.PrintWithOptions(os.Stdout, &printOptions)
 	return nil
 }
 
-func disassemble(ctx *cli.Context) error {
+// Get entry point given either as an address or a label in program
+func entryAddress(program *prog.Program, entry string) (uint, error) {
+	if addr, err := strconv.ParseUint(entry, 10, 32); err == nil {
+		return uint(addr), nil
+	}
+	if addr, ok := program.Labels[entry]; ok {
+		return addr, nil
+	}
+	return 0, fmt.Errorf("entry point '%s' is neither an address nor a label in program", entry)
+}
+
+// Write annotated listing of assembly code in file at filepath as HTML or Markdown
+func writeListing(filepath string, formatName string) error {
 	errorColor := color.New(color.FgRed)
-	filepath := ctx.Args().First()
-	program, err := disasm.DisassembleFile(filepath)
+	format, err := listing.ParseFormat(formatName)
+	if err == nil {
+		var src []byte
+		src, err = os.ReadFile(filepath)
+		if err == nil {
+			err = listing.Write(os.Stdout, path.Base(filepath), src, format)
+		}
+	}
 	if err != nil {
 		errorColor.Fprintf(os.Stderr, "Error: ")
 		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+// Get options for separating code from data given on command line
+func disassemblyOptions(ctx *cli.Context) (*disasm.Options, error) {
+	options := disasm.Options{
+		Entry:    ctx.Uint("entry"),
+		EntrySet: ctx.IsSet("entry"),
+		Hints:    make(map[uint]disasm.WordKind),
+		AllCode:  ctx.Bool("all-code"),
+		NoPseudo: ctx.Bool("no-pseudo"),
+		Strict:   ctx.Bool("strict"),
+	}
+
+	for _, hint := range []struct {
+		flag string
+		kind disasm.WordKind
+	}{{"code", disasm.Code}, {"data", disasm.Data}} {
+		addresses, err := utils.ParseAddressRanges(ctx.String(hint.flag))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s hint: %w", hint.flag, err)
+		}
+		for _, addr := range addresses {
+			options.Hints[addr] = hint.kind
+		}
+	}
+	return &options, nil
+}
+
+// Print error from loading file at filepath. Every problem in a malformed
+// machine code file is reported on its own line, prefixed with its location
+func printLoadError(filepath string, err error) {
+	errorColor := color.New(color.FgRed)
+
+	var syntaxErrors disasm.SyntaxErrors
+	if !errors.As(err, &syntaxErrors) {
+		errorColor.Fprintf(os.Stderr, "Error: ")
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
+	}
+	for _, e := range syntaxErrors {
+		errorColor.Fprintf(os.Stderr, "Error: ")
+		fmt.Fprintf(os.Stderr, "%s:%d:%d: %s\n", filepath, e.Line, e.Column, e.Msg)
+	}
+}
+
+func disassemble(ctx *cli.Context) error {
+	filepath := ctx.Args().First()
+	options, err := disassemblyOptions(ctx)
+	var program *prog.Program
+	if err == nil {
+		program, err = disasm.DisassembleFileWithOptions(filepath, options)
+	}
+	if err != nil {
+		printLoadError(filepath, err)
 		return nil
 	}
 
+	if ctx.Bool("source") {
+		return disasm.WriteSource(os.Stdout, program)
+	}
 	program.PrintWithOptions(os.Stdout, &printOptions)
 	return nil
 }
 
+// Decompile machine code file into structured pseudo-code. Assembly code files
+// are assembled first, so that their labels name functions and variables
+func decompile(ctx *cli.Context) error {
+	filepath := ctx.Args().First()
+	options, err := disassemblyOptions(ctx)
+	var program *prog.Program
+	if err == nil && strings.HasSuffix(filepath, ".ct33") {
+		program, err = asm.AssembleFile(filepath)
+		if err == nil {
+			options.Symbols = program.Labels
+			if !ctx.IsSet("entry") {
+				options.Entry = program.Entry
+			}
+			program, err = disasm.DisassembleMemoryWithOptions(program.MachineCode(), options)
+		}
+	} else if err == nil {
+		program, err = disasm.DisassembleFileWithOptions(filepath, options)
+	}
+	if err != nil {
+		printLoadError(filepath, err)
+		return cli.Exit("", 1)
+	}
+
+	return decomp.Write(os.Stdout, program)
+}
+
 func runCode(ctx *cli.Context) error {
 	errorColor := color.New(color.FgRed)
 	filepath := ctx.Args().First()
@@ -55,24 +186,155 @@ func runCode(ctx *cli.Context) error {
 	useTextOutput := ctx.Bool("text")
 
 	var program *prog.Program
+	var comp *sim.Computer
 	var err error
 	if strings.HasSuffix(filepath, ".ct33") {
 		program, err = asm.AssembleFile(filepath)
 	} else if strings.HasSuffix(filepath, ".machine") {
-		program, err = disasm.DisassembleFile(filepath)
+		// machine code doesn't tell us what is data, so any word may be executed
+		program, err = disasm.DisassembleFileWithOptions(filepath, &disasm.Options{AllCode: true})
+	} else if strings.HasSuffix(filepath, ".json") {
+		// continue from a snapshot
+		comp, err = sim.NewComputerFile(filepath)
+	} else {
+		err = fmt.Errorf("file '%s' is neither assembly code, machine code nor a snapshot", filepath)
 	}
 
+	if err == nil && comp == nil {
+		comp, err = sim.NewComputer(program)
+	}
 	if err != nil {
+		printLoadError(filepath, err)
+		return nil
+	}
+	if comp.Costs, err = loadCostModel(ctx); err != nil {
 		errorColor.Fprintf(os.Stderr, "Error: ")
 		fmt.Fprintf(os.Stderr, "%v\n", err)
-		return nil
+		return cli.Exit("", 1)
+	}
+	if err = device.Attach(comp, ctx.StringSlice("device")); err != nil {
+		errorColor.Fprintf(os.Stderr, "Error: ")
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return cli.Exit("", 1)
+	}
+
+	// draw first screen as program runs and let keys typed reach the keyboard,
+	// unless running headless where screens are written out as text at the end
+	live := !ctx.Bool("headless") && !verbose && readline.IsTerminal(int(os.Stdout.Fd()))
+	var screens []*device.Screen
+	var display *device.Display
+	for _, mapped := range comp.Devices() {
+		switch dev := mapped.Device.(type) {
+		case *device.Screen:
+			screens = append(screens, dev)
+			if live && display == nil {
+				display = device.NewDisplay(os.Stdout, dev)
+				display.Draw()
+			}
+		case *device.Keyboard:
+			if live && readline.IsTerminal(int(os.Stdin.Fd())) {
+				restore, err := readKeys(dev)
+				if err != nil {
+					errorColor.Fprintf(os.Stderr, "Error: ")
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					return cli.Exit("", 1)
+				}
+				defer restore()
+			}
+		}
+	}
+
+	var dataCache *cache.Cache
+	if spec := ctx.String("cache"); spec != "" {
+		config, err := cache.ParseConfig(spec)
+		if err == nil {
+			dataCache, err = cache.New(config)
+		}
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: ")
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return cli.Exit("", 1)
+		}
+		comp.Cache = dataCache
+	}
+
+	var tracers sim.Tracers
+	var profiler *profile.Profile
+	if ctx.Bool("profile") || ctx.String("chrome-trace") != "" {
+		profiler = profile.New(comp)
+		tracers = append(tracers, profiler)
+	}
+
+	var evaluator *predict.Evaluator
+	var predictorNames []string
+	if names := ctx.String("predict"); names == "all" {
+		predictorNames = predict.Names
+	} else if names != "" {
+		predictorNames = strings.Split(names, ",")
+	}
+	if len(predictorNames) > 0 {
+		predictors := make([]predict.Predictor, len(predictorNames))
+		for i, name := range predictorNames {
+			if predictors[i], err = predict.New(name); err != nil {
+				errorColor.Fprintf(os.Stderr, "Error: ")
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				return cli.Exit("", 1)
+			}
+		}
+		evaluator = predict.NewEvaluator(comp, predictors...)
+		tracers = append(tracers, evaluator)
+	}
+
+	var pipe *pipeline.Pipeline
+	if ctx.Bool("pipeline") {
+		pipe = pipeline.New(pipeline.Options{
+			Forwarding:  ctx.Bool("forwarding"),
+			DiagramSize: ctx.Int("pipeline-diagram"),
+		})
+		// pipeline needs a predictor of its own, since predictors learn as they go
+		if len(predictorNames) > 0 {
+			pipe.Predictor, _ = predict.New(predictorNames[0])
+		}
+		tracers = append(tracers, pipe)
+	}
+
+	if tracePath := ctx.String("trace"); tracePath != "" {
+		file, err := os.Create(tracePath)
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: ")
+			fmt.Fprintf(os.Stderr, "could not create trace file: %v\n", err)
+			return cli.Exit("", 1)
+		}
+		defer file.Close()
+
+		var tracer interface {
+			sim.Tracer
+			Flush() error
+		}
+		if strings.HasSuffix(tracePath, ".csv") {
+			tracer = sim.NewCSVTracer(file)
+		} else {
+			tracer = sim.NewJSONTracer(file)
+		}
+		tracers = append(tracers, tracer)
+		defer func() {
+			if err := tracer.Flush(); err != nil {
+				errorColor.Fprintf(os.Stderr, "Error: ")
+				fmt.Fprintf(os.Stderr, "could not write trace: %v\n", err)
+			}
+		}()
+	}
+
+	if len(tracers) > 0 {
+		comp.Tracer = tracers
 	}
 
-	comp := sim.NewComputer(program)
+	var result sim.Result
+	limits := runLimits(ctx)
 	// comp.LoadInputs(os.Stdin)
 
 	if verbose {
-		pContext := prog.NewPrintContext(program.Labels, &printOptions)
+		pContext := prog.NewPrintContext(comp.Labels(), &printOptions)
 
 		var group sync.WaitGroup
 		group.Add(1)
@@ -82,9 +344,7 @@ func runCode(ctx *cli.Context) error {
 			group.Done()
 		}()
 
-		// NOTE: I am arbitrarily restricting program from running more than 5000 instructions
-		// that is just to not end up in some endless loop
-		comp.RunChannel(5000, channel)
+		result = comp.RunLimited(limits, channel)
 		close(channel)
 
 		// wait until executed instuctions have been printed out to consol
@@ -94,13 +354,15 @@ func runCode(ctx *cli.Context) error {
 		fmt.Println()
 		fmt.Println(comp.String())
 
-		// check if there was any errors
-		if comp.Err != nil {
-			errorColor.Fprintf(os.Stderr, "Error: ")
-			fmt.Fprintf(os.Stderr, "program execution terminated early because %v\n", comp.Err)
+		if !result.Fault() {
+			fmt.Printf("Program stopped because %v\n", result.Err())
 		}
 	} else {
-		comp.Run(5000)
+		result = comp.RunLimited(limits, nil)
+		if display != nil {
+			// show changes made since screen was last drawn
+			display.Draw()
+		}
 		for _, n := range comp.Outputs() {
 			if useTextOutput {
 				fmt.Printf("%c", n)
@@ -111,13 +373,275 @@ func runCode(ctx *cli.Context) error {
 		fmt.Println()
 	}
 
+	for _, screen := range screens {
+		if display == nil || screen != display.Screen() {
+			screen.WriteText(os.Stdout)
+		}
+	}
+	if ctx.Bool("profile") {
+		profiler.WriteReport(os.Stdout)
+	}
+	if evaluator != nil {
+		fmt.Println()
+		evaluator.WriteReport(os.Stdout)
+	}
+	if pipe != nil {
+		fmt.Println()
+		pipe.WriteDiagram(os.Stdout)
+		fmt.Println()
+		pipe.WriteSummary(os.Stdout)
+	}
+	if dataCache != nil {
+		fmt.Println()
+		dataCache.WriteReport(os.Stdout, comp)
+	}
+	if chromePath := ctx.String("chrome-trace"); chromePath != "" {
+		if err := writeChromeTrace(chromePath, profiler); err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: ")
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+
+	if snapshotPath := ctx.String("snapshot"); snapshotPath != "" {
+		if err := comp.SaveSnapshotFile(snapshotPath); err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: ")
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+
+	if result.Fault() {
+		errorColor.Fprintf(os.Stderr, "Error: ")
+		fmt.Fprintf(os.Stderr, "program execution terminated early because %v\n", result.Err())
+		switch result.Reason {
+		case sim.StepLimit:
+			fmt.Fprintf(os.Stderr, "Use --max-steps to allow more than %d steps\n", limits.MaxSteps)
+		case sim.Timeout:
+			fmt.Fprintf(os.Stderr, "Use --timeout to allow more than %v\n", limits.Timeout)
+		}
+		return cli.Exit("", 1)
+	}
 	return nil
 }
 
+// Pass keys typed in terminal on to keyboard as they are pressed, until the
+// returned function is called to put the terminal back to normal. Since the
+// terminal no longer turns Ctrl-C into a signal, it is handled here
+func readKeys(kb *device.Keyboard) (func(), error) {
+	fd := int(os.Stdin.Fd())
+	state, err := readline.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("could not read keys from terminal: %w", err)
+	}
+	restore := func() { readline.Restore(fd, state) }
+
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			key, _, err := reader.ReadRune()
+			if err != nil {
+				return
+			}
+			if key == 3 {
+				restore()
+				os.Exit(130)
+			}
+			if key == '\r' {
+				key = '\n'
+			}
+			kb.Press(key)
+		}
+	}()
+	return restore, nil
+}
+
+// Write subroutine calls recorded by profiler to file at path as Chrome trace events
+func writeChromeTrace(path string, profiler *profile.Profile) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create Chrome trace: %w", err)
+	}
+	defer file.Close()
+
+	return profiler.WriteChromeTrace(file)
+}
+
+// Get limits on how long programs may run given on command line
+func runLimits(ctx *cli.Context) sim.Limits {
+	return sim.Limits{
+		MaxSteps:    ctx.Int("max-steps"),
+		Timeout:     ctx.Duration("timeout"),
+		DetectLoops: ctx.Bool("detect-loops"),
+	}
+}
+
+// Cost model given on command line, or nil to use the default one
+func loadCostModel(ctx *cli.Context) (*sim.CostModel, error) {
+	if path := ctx.String("costs"); path != "" {
+		return sim.LoadCostModelFile(path)
+	}
+	return nil, nil
+}
+
+// Flag for giving cycles taken by each instruction
+func createCostsFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "costs",
+		Usage: "JSON file giving clock cycles taken by each instruction",
+	}
+}
+
+// Flag for attaching devices to memory, which may be given several times
+func createDeviceFlag() cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:  "device",
+		Usage: "attach device given as name@address:option=value:... where name is one of " + strings.Join(device.Names(), ", "),
+	}
+}
+
+// Flags for limiting how long programs may run
+func createLimitFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:  "max-steps",
+			Usage: "stop program after executing this many instructions, 0 for no limit",
+			Value: sim.DefaultLimits.MaxSteps,
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "stop program after running this long, such as 2s, 0 for no limit",
+			Value: sim.DefaultLimits.Timeout,
+		},
+		&cli.BoolFlag{
+			Name:  "detect-loops",
+			Usage: "stop program when it gets stuck in an infinite loop",
+			Value: sim.DefaultLimits.DetectLoops,
+		},
+	}
+}
+
+func formatFiles(ctx *cli.Context) error {
+	errorColor := color.New(color.FgRed)
+	check := ctx.Bool("check")
+	showDiff := ctx.Bool("diff")
+
+	// with no files given we act as a filter from stdin to stdout
+	if ctx.Args().Len() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		formatted, err := format.Source(src)
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: ")
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return cli.Exit("", 1)
+		}
+		os.Stdout.Write(formatted)
+		return nil
+	}
+
+	failed := false
+	for _, filepath := range ctx.Args().Slice() {
+		src, err := os.ReadFile(filepath)
+		if err == nil {
+			var formatted []byte
+			formatted, err = format.Source(src)
+			if err == nil && !bytes.Equal(src, formatted) {
+				switch {
+				case showDiff:
+					fmt.Print(utils.Diff(filepath, filepath+" (formatted)", lines(src), lines(formatted)))
+					failed = failed || check
+				case check:
+					fmt.Println(filepath)
+					failed = true
+				default:
+					err = os.WriteFile(filepath, formatted, 0644)
+				}
+			}
+		}
+
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: ")
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filepath, err)
+			failed = true
+		}
+	}
+
+	if failed {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+func lines(text []byte) []string {
+	return strings.Split(strings.TrimSuffix(string(text), "\n"), "\n")
+}
+
+func fixFiles(ctx *cli.Context) error {
+	errorColor := color.New(color.FgRed)
+	removedColor := color.New(color.FgRed)
+	addedColor := color.New(color.FgGreen)
+
+	failed := false
+	for _, filepath := range ctx.Args().Slice() {
+		src, err := os.ReadFile(filepath)
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: ")
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			failed = true
+			continue
+		}
+
+		fixed, fixes, errs := asm.FixSource(string(src))
+		for _, fix := range fixes {
+			fmt.Printf("%s:%d: %s\n", filepath, fix.Line, fix.Message)
+			removedColor.Printf("- %s\n", fix.Old)
+			for _, line := range fix.New {
+				addedColor.Printf("+ %s\n", line)
+			}
+		}
+
+		if len(fixes) > 0 {
+			if err := os.WriteFile(filepath, []byte(fixed), 0644); err != nil {
+				errorColor.Fprintf(os.Stderr, "Error: ")
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				failed = true
+			}
+		}
+
+		for _, err := range errs {
+			errorColor.Fprintf(os.Stderr, "Error: ")
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filepath, err)
+			failed = true
+		}
+	}
+
+	if failed {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+func languageServer(ctx *cli.Context) error {
+	return lsp.NewServer().Serve(os.Stdin, os.Stdout)
+}
+
 func debug(ctx *cli.Context) error {
 	errorColor := color.New(color.FgRed)
 
 	var comp sim.Computer
+	comp.Limits = runLimits(ctx)
+	comp.HistorySize = ctx.Int("history")
+	costs, err := loadCostModel(ctx)
+	if err != nil {
+		errorColor.Fprintf(os.Stderr, "Error: ")
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+	}
+	comp.Costs = costs
+	if err := device.Attach(&comp, ctx.StringSlice("device")); err != nil {
+		errorColor.Fprintf(os.Stderr, "Error: ")
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+	}
 	args := ctx.Args()
 	if args.Len() > 0 {
 		err := comp.LoadFile(args.First())
@@ -206,7 +730,36 @@ func main() {
 		Aliases: []string{"disasm"},
 		Usage:   "disassemble a file containing calcutron-33 machine code",
 		Action:  disassemble,
-		Flags:   createFlags(DISASSEMBLY),
+		Flags: append(createFlags(DISASSEMBLY),
+			&cli.UintFlag{
+				Name:  "entry",
+				Usage: "address where execution starts, used to tell code from data",
+			},
+			&cli.StringFlag{
+				Name:  "code",
+				Usage: "addresses to always treat as code, e.g. 3,10-12",
+			},
+			&cli.StringFlag{
+				Name:  "data",
+				Usage: "addresses to always treat as data, e.g. 3,10-12",
+			},
+			&cli.BoolFlag{
+				Name:  "all-code",
+				Usage: "treat every word as an instruction",
+			},
+			&cli.BoolFlag{
+				Name:  "no-pseudo",
+				Usage: "show base instructions rather than pseudo instructions such as INC and MOVE",
+			},
+			&cli.BoolFlag{
+				Name:  "strict",
+				Usage: "require exactly one 4-digit machine code word per line",
+			},
+			&cli.BoolFlag{
+				Name:  "source",
+				Usage: "write assembly code which assembles back into the same machine code",
+			},
+		),
 	}
 
 	assembleCmd := cli.Command{
@@ -214,7 +767,46 @@ func main() {
 		Aliases: []string{"asm"},
 		Usage:   "assemble a calcutron-33 assembly code file",
 		Action:  assemble,
-		Flags:   createFlags(ASSEMBLY),
+		Flags: append(createFlags(ASSEMBLY),
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "output format: text, html or md for an annotated listing",
+				Value: "text",
+			},
+			&cli.BoolFlag{
+				Name:  "header",
+				Usage: "write machine code file with header holding name, entry point, symbols and checksum",
+			},
+			&cli.StringFlag{
+				Name:  "entry",
+				Usage: "label or address where execution starts",
+			},
+		),
+	}
+
+	decompileCmd := cli.Command{
+		Name:      "decompile",
+		Usage:     "turn calcutron-33 machine code into pseudo-code with loops and if/else statements",
+		ArgsUsage: "file",
+		Action:    decompile,
+		Flags: []cli.Flag{
+			&cli.UintFlag{
+				Name:  "entry",
+				Usage: "address where execution starts, used to tell code from data",
+			},
+			&cli.StringFlag{
+				Name:  "code",
+				Usage: "addresses to always treat as code, e.g. 3,10-12",
+			},
+			&cli.StringFlag{
+				Name:  "data",
+				Usage: "addresses to always treat as data, e.g. 3,10-12",
+			},
+			&cli.BoolFlag{
+				Name:  "strict",
+				Usage: "require exactly one 4-digit machine code word per line",
+			},
+		},
 	}
 
 	runFlags := createFlags(SIMULATION)
@@ -228,11 +820,46 @@ func main() {
 	}
 	runFlags = append(runFlags, &verboseFlag)
 	runFlags = append(runFlags, &textFlag)
+	runFlags = append(runFlags, createLimitFlags()...)
+	runFlags = append(runFlags, createCostsFlag(), createDeviceFlag())
+	runFlags = append(runFlags, &cli.StringFlag{
+		Name:  "snapshot",
+		Usage: "save state of computer as JSON to given file when program stops",
+	}, &cli.StringFlag{
+		Name:  "trace",
+		Usage: "record every executed instruction to given file as JSON Lines, or CSV if it ends with .csv",
+	}, &cli.BoolFlag{
+		Name:  "profile",
+		Usage: "report how often each instruction, loop and memory address was used",
+	}, &cli.StringFlag{
+		Name:  "chrome-trace",
+		Usage: "save timeline of subroutine calls to given file as Chrome trace event JSON",
+	}, &cli.BoolFlag{
+		Name:  "pipeline",
+		Usage: "show how a five stage pipeline would execute the program",
+	}, &cli.BoolFlag{
+		Name:  "forwarding",
+		Usage: "let pipeline forward results to instructions needing them instead of waiting for write back",
+		Value: true,
+	}, &cli.IntFlag{
+		Name:  "pipeline-diagram",
+		Usage: "number of instructions shown in pipeline diagram",
+		Value: pipeline.DefaultDiagramSize,
+	}, &cli.StringFlag{
+		Name:  "predict",
+		Usage: "compare branch predictors given as a comma separated list of " + strings.Join(predict.Names, ", ") + " or all. The first is used by --pipeline",
+	}, &cli.BoolFlag{
+		Name:  "headless",
+		Usage: "write contents of screen devices as text when program stops, instead of drawing them as it runs",
+	}, &cli.StringFlag{
+		Name:  "cache",
+		Usage: "simulate data cache given as default or a comma separated list of size=16,line=4,ways=1,penalty=10,write-back or write-through",
+	})
 
 	runCmd := cli.Command{
 		Name:    "run",
 		Aliases: []string{"simulate", "sim"},
-		Usage:   "run a calcutron-33 machine code file",
+		Usage:   "run a calcutron-33 machine code file, or continue from a snapshot",
 		Action:  runCode,
 		Flags:   runFlags,
 	}
@@ -242,6 +869,41 @@ func main() {
 		Aliases: []string{"dbg"},
 		Usage:   "debug calcutron program",
 		Action:  debug,
+		Flags: append(createLimitFlags(), &cli.IntFlag{
+			Name:  "history",
+			Usage: "number of executed instructions which can be undone with back",
+			Value: sim.DefaultHistorySize,
+		}, createCostsFlag(), createDeviceFlag()),
+	}
+
+	fmtCmd := cli.Command{
+		Name:      "fmt",
+		Usage:     "rewrite calcutron-33 assembly code files in canonical layout",
+		ArgsUsage: "[files...]",
+		Action:    formatFiles,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "check",
+				Usage: "list files which are not formatted and exit with error status",
+			},
+			&cli.BoolFlag{
+				Name:  "diff",
+				Usage: "show changes formatting would make instead of rewriting files",
+			},
+		},
+	}
+
+	fixCmd := cli.Command{
+		Name:      "fix",
+		Usage:     "fix common mistakes in calcutron-33 assembly code files",
+		ArgsUsage: "files...",
+		Action:    fixFiles,
+	}
+
+	lspCmd := cli.Command{
+		Name:   "lsp",
+		Usage:  "run language server for calcutron-33 assembly code over stdin and stdout",
+		Action: languageServer,
 	}
 
 	app := &cli.App{
@@ -249,8 +911,12 @@ func main() {
 		Commands: []*cli.Command{
 			&assembleCmd,
 			&disassembleCmd,
+			&decompileCmd,
 			&runCmd,
 			&dbgCmd,
+			&fmtCmd,
+			&fixCmd,
+			&lspCmd,
 		},
 	}
 
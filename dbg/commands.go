@@ -33,6 +33,12 @@ type SetCmd struct{}
 type AsmCmd struct{}
 type ResetCmd struct{}
 type MemoryCmd struct{}
+type SaveCmd struct{}
+type ReloadCmd struct{}
+type BreakCmd struct{}
+type ClearCmd struct{}
+type BackCmd struct{}
+type ReverseContinueCmd struct{}
 
 func (cmd *HelpCmd) Name() string {
 	return "help"
@@ -134,7 +140,9 @@ SYNOPSIS
 	load filepath
 DESCRIPTION
 	loads either machine code or source code into memory
-	ready for execution.`)
+	ready for execution. Files ending in .json are snapshots
+	written by the save command, which restore the complete
+	state of the computer.`)
 }
 
 func (cmd *LoadCmd) Action(writer io.Writer, comp *sim.Computer, args []string) error {
@@ -160,7 +168,10 @@ DESCRIPTION
 
 func (cmd *ListCmd) Action(writer io.Writer, comp *sim.Computer, args []string) error {
 	memory := comp.ProgramSlice()
-	program, err := disasm.DisassembleMemory(memory)
+	program, err := disasm.DisassembleMemoryWithOptions(memory, &disasm.Options{
+		Entry:   comp.Entry(),
+		Symbols: comp.Labels(),
+	})
 	if err != nil {
 		return err
 	}
@@ -168,6 +179,7 @@ func (cmd *ListCmd) Action(writer io.Writer, comp *sim.Computer, args []string)
 		Address:     true,
 		MachineCode: true,
 		SourceCode:  true,
+		Labels:      true,
 	})
 	err = comp.Err
 	comp.Err = nil
@@ -185,7 +197,8 @@ func (cmd *StatusCmd) Help(writer io.Writer) {
 SYNOPSIS
 	status
 DESCRIPTION
-	shows internal status of computer.`)
+	shows internal status of computer. When interrupts can be used it also
+	shows whether they are enabled, the interrupt vector and pending interrupts.`)
 }
 
 func (cmd *StatusCmd) Action(writer io.Writer, comp *sim.Computer, args []string) error {
@@ -210,21 +223,22 @@ DESCRIPTION
 }
 
 func (cmd *NextCmd) Action(writer io.Writer, comp *sim.Computer, args []string) error {
-	inst := comp.Instruction()
-
-	prog.AddressColor.Fprintf(writer, "%02d ", comp.PC())
-	prog.GrayColor.Fprintf(writer, "%04d ", inst.MachineCode())
-	fmt.Fprintln(writer, inst.SourceCode())
-
-	comp.Step()
-
-	switch inst.Opcode() {
-	case prog.BRA, prog.BEQ, prog.BGT, prog.BLT, prog.JMP:
-		comp.PrintProgramCounterAndSteps(writer)
-	case prog.HLT:
-		break
-	default:
-		comp.PrintRegs(writer, inst.UniqueRegisters()...)
+	result := comp.Step()
+
+	// no instruction to show when program counter is outside memory
+	if inst := result.Instruction; inst != nil {
+		prog.AddressColor.Fprintf(writer, "%02d ", result.PC)
+		prog.GrayColor.Fprintf(writer, "%04d ", inst.MachineCode())
+		fmt.Fprintln(writer, inst.SourceCode())
+
+		switch inst.Opcode() {
+		case prog.BRA, prog.BEQ, prog.BGT, prog.BLT, prog.JMP:
+			comp.PrintProgramCounterAndSteps(writer)
+		case prog.HLT:
+			break
+		default:
+			comp.PrintRegs(writer, inst.UniqueRegisters()...)
+		}
 	}
 
 	err := comp.Err
@@ -244,6 +258,8 @@ SYNOPSIS
 	run [instruction]
 DESCRIPTION
 	Execute loaded from from start until a halting instruction is met or input is exausted.
+	Execution also stops at the limits given with --max-steps, --timeout and --detect-loops
+	when starting the debugger.
 	If an assembly code instruction is specified it will be parsed and run`)
 }
 
@@ -253,7 +269,7 @@ func (cmd *RunCmd) Action(writer io.Writer, comp *sim.Computer, args []string) e
 	if len(line) > 0 && utils.AllDigits(line) {
 		machinecode, _ := strconv.Atoi(line)
 		inst := disasm.DisassembleInstruction(uint(machinecode))
-		inst.Run(comp)
+		comp.Execute(inst)
 		return nil
 	} else if len(line) > 0 {
 		labels := make(prog.SymbolTable)
@@ -262,13 +278,16 @@ func (cmd *RunCmd) Action(writer io.Writer, comp *sim.Computer, args []string) e
 			return nil
 		}
 		if inst != nil {
-			inst.Run(comp)
+			comp.Execute(inst)
 		}
 		return nil
 	}
 
 	memory := comp.ProgramSlice()
-	program, err := disasm.DisassembleMemory(memory)
+	program, err := disasm.DisassembleMemoryWithOptions(memory, &disasm.Options{
+		Entry:   comp.Entry(),
+		Symbols: comp.Labels(),
+	})
 	if err != nil {
 		return err
 	}
@@ -287,9 +306,7 @@ func (cmd *RunCmd) Action(writer io.Writer, comp *sim.Computer, args []string) e
 		group.Done()
 	}()
 
-	// NOTE: Arbitrary limiting number of instruction we can run to 5000 to avoid
-	// getting hung up
-	comp.RunChannel(5000, channel)
+	result := comp.RunLimited(comp.Limits, channel)
 	close(channel)
 
 	// wait until executed instuctions have been printed out to consol
@@ -297,8 +314,14 @@ func (cmd *RunCmd) Action(writer io.Writer, comp *sim.Computer, args []string) e
 
 	// Print out status of computer
 	fmt.Fprintln(writer)
+	if result.Reason == sim.Breakpoint {
+		fmt.Fprintf(writer, "Stopped at breakpoint at address %02d\n\n", result.PC)
+	}
 	fmt.Fprintln(writer, comp.String())
-	return nil
+
+	err = comp.Err
+	comp.Err = nil
+	return err
 }
 
 func (cmd *PrintCmd) Name() string {
@@ -453,6 +476,48 @@ func (cmd *ResetCmd) Action(writer io.Writer, comp *sim.Computer, args []string)
 	return nil
 }
 
+func (cmd *ReloadCmd) Name() string {
+	return "reload"
+}
+
+func (cmd *ReloadCmd) Help(writer io.Writer) {
+	fmt.Fprintln(writer,
+		`NAME
+	reload -- reset computer and memory to the state program was loaded in
+SYNOPSIS
+	reload
+DESCRIPTION
+	like reset, but also undo changes the program has made to memory
+	so that programs writing to memory can run over again`)
+}
+
+func (cmd *ReloadCmd) Action(writer io.Writer, comp *sim.Computer, args []string) error {
+	comp.Reload()
+	return nil
+}
+
+func (cmd *SaveCmd) Name() string {
+	return "save"
+}
+
+func (cmd *SaveCmd) Help(writer io.Writer) {
+	fmt.Fprintln(writer,
+		`NAME
+	save -- save snapshot of computer to file
+SYNOPSIS
+	save filepath
+DESCRIPTION
+	save program counter, registers, memory, inputs, outputs, step
+	count and labels as JSON. Use load to restore the snapshot`)
+}
+
+func (cmd *SaveCmd) Action(writer io.Writer, comp *sim.Computer, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("cannot save snapshot, missing file argument")
+	}
+	return comp.SaveSnapshotFile(args[0])
+}
+
 func (cmd *MemoryCmd) Name() string {
 	return "memory"
 }
@@ -470,15 +535,9 @@ DESCRIPTION
 func (cmd *MemoryCmd) Action(writer io.Writer, comp *sim.Computer, args []string) error {
 	var addr uint
 	if len(args) > 0 {
-		addrStr := args[0]
-		var found bool
-		if addr, found = comp.LookupSymbol(addrStr); !found {
-			var addrSigned int
-			addrSigned, err := strconv.Atoi(addrStr)
-			if err != nil {
-				return fmt.Errorf("unable to parse address %s for memory instruction because %w", addrStr, err)
-			}
-			addr = uint(addrSigned)
+		var err error
+		if addr, err = parseAddress(comp, args[0]); err != nil {
+			return fmt.Errorf("%w for memory instruction", err)
 		}
 	}
 
@@ -498,6 +557,135 @@ func (cmd *MemoryCmd) Action(writer io.Writer, comp *sim.Computer, args []string
 	return nil
 }
 
+func (cmd *BreakCmd) Name() string {
+	return "break"
+}
+
+func (cmd *BreakCmd) Help(writer io.Writer) {
+	fmt.Fprintln(writer,
+		`NAME
+	break -- stop running program at address
+SYNOPSIS
+	break [address]
+DESCRIPTION
+	set breakpoint at address, which can be a label. Running the program stops
+	before executing the instruction at a breakpoint. Without an address all
+	breakpoints are listed`)
+}
+
+func (cmd *BreakCmd) Action(writer io.Writer, comp *sim.Computer, args []string) error {
+	if len(args) == 0 {
+		for _, addr := range comp.Breakpoints() {
+			prog.AddressColor.Fprintf(writer, "%02d\n", addr)
+		}
+		return nil
+	}
+	addr, err := parseAddress(comp, args[0])
+	if err != nil {
+		return err
+	}
+	comp.SetBreakpoint(addr)
+	return nil
+}
+
+func (cmd *ClearCmd) Name() string {
+	return "clear"
+}
+
+func (cmd *ClearCmd) Help(writer io.Writer) {
+	fmt.Fprintln(writer,
+		`NAME
+	clear -- remove breakpoint
+SYNOPSIS
+	clear address
+DESCRIPTION
+	remove breakpoint at address, which can be a label`)
+}
+
+func (cmd *ClearCmd) Action(writer io.Writer, comp *sim.Computer, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("missing address of breakpoint to clear")
+	}
+	addr, err := parseAddress(comp, args[0])
+	if err != nil {
+		return err
+	}
+	comp.ClearBreakpoint(addr)
+	return nil
+}
+
+func (cmd *BackCmd) Name() string {
+	return "back"
+}
+
+func (cmd *BackCmd) Help(writer io.Writer) {
+	fmt.Fprintln(writer,
+		`NAME
+	back -- undo executed instructions
+SYNOPSIS
+	back [steps]
+DESCRIPTION
+	step backwards by undoing the last executed instruction, or the given number
+	of instructions. Registers, memory, inputs and outputs are restored to what
+	they were before. How far back you can go is set with --history when
+	starting the debugger`)
+}
+
+func (cmd *BackCmd) Action(writer io.Writer, comp *sim.Computer, args []string) error {
+	steps := 1
+	if len(args) > 0 {
+		var err error
+		if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+			return fmt.Errorf("number of steps to go back must be a positive number, not %s", args[0])
+		}
+	}
+
+	for i := 0; i < steps; i++ {
+		if !comp.StepBack() {
+			if i == 0 {
+				return fmt.Errorf("no executed instructions to undo")
+			}
+			break
+		}
+	}
+	printCurrentInstruction(writer, comp)
+	return nil
+}
+
+func (cmd *ReverseContinueCmd) Name() string {
+	return "reverse-continue"
+}
+
+func (cmd *ReverseContinueCmd) Help(writer io.Writer) {
+	fmt.Fprintln(writer,
+		`NAME
+	reverse-continue -- run backwards to previous breakpoint
+SYNOPSIS
+	reverse-continue
+DESCRIPTION
+	undo executed instructions until reaching a breakpoint, or until there is
+	nothing more to undo`)
+}
+
+func (cmd *ReverseContinueCmd) Action(writer io.Writer, comp *sim.Computer, args []string) error {
+	if comp.ReverseContinue() == 0 {
+		return fmt.Errorf("no executed instructions to undo")
+	}
+	printCurrentInstruction(writer, comp)
+	return nil
+}
+
+// Show instruction about to be executed, and state of computer
+func printCurrentInstruction(writer io.Writer, comp *sim.Computer) {
+	if inst := comp.Instruction(); inst != nil {
+		prog.AddressColor.Fprintf(writer, "%02d ", comp.PC())
+		prog.GrayColor.Fprintf(writer, "%04d ", inst.MachineCode())
+		fmt.Fprintln(writer, inst.SourceCode())
+	}
+	fmt.Fprintln(writer)
+	fmt.Fprint(writer, comp.String())
+}
+
 var commands = [...]Command{
 	new(HelpCmd),
 	new(InputCmd),
@@ -512,6 +700,24 @@ var commands = [...]Command{
 	new(AsmCmd),
 	new(ResetCmd),
 	new(MemoryCmd),
+	new(SaveCmd),
+	new(ReloadCmd),
+	new(BreakCmd),
+	new(ClearCmd),
+	new(BackCmd),
+	new(ReverseContinueCmd),
+}
+
+// Get address given either as a number or a label
+func parseAddress(comp *sim.Computer, addrStr string) (uint, error) {
+	if addr, found := comp.LookupSymbol(addrStr); found {
+		return addr, nil
+	}
+	addr, err := strconv.Atoi(addrStr)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse address %s because %w", addrStr, err)
+	}
+	return uint(addr), nil
 }
 
 // Lookup command with given name. Returns nil if command
@@ -1,7 +1,9 @@
 package dbg
 
 import (
+	"bytes"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/ordovician/calcutron/sim"
@@ -40,3 +42,17 @@ func TestReadline(t *testing.T) {
 		RunCommand(os.Stdout, line, &comp)
 	}
 }
+
+func TestListUsesHeader(t *testing.T) {
+	src := "#version 1\n#entry 2\n#symbol start 2\n0000\n0000\n5109\n9100\n0000\n"
+	var comp sim.Computer
+	if err := comp.LoadMachineCode(strings.NewReader(src)); err != nil {
+		t.Fatalf("failed to load machine code because %v", err)
+	}
+
+	var buffer bytes.Buffer
+	RunCommand(&buffer, "list", &comp)
+	if listing := buffer.String(); !strings.Contains(listing, "start:") || !strings.Contains(listing, "INP") {
+		t.Errorf("expected listing to start code at entry labelled start, got\n%s", listing)
+	}
+}
@@ -0,0 +1,385 @@
+// Package decomp turns a disassembled Calcutron-33 program into structured
+// pseudo-code, recovering loops and if/else statements from its control flow.
+//
+// Every subroutine called with CALL, or the JMP x9 it stands for, becomes a
+// function of its own. Backward branches become while and do-while loops,
+// forward branches become if and if/else statements. Control flow which does
+// not fit these shapes is shown with goto, so nothing is ever left out.
+package decomp
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/ordovician/calcutron/prog"
+	"golang.org/x/exp/slices"
+)
+
+// Labels made up by the disassembler rather than coming from a symbol table
+var syntheticLabel = regexp.MustCompile(`^(L|loop_)\d+$`)
+
+// Kind of control flow change made by an instruction
+type flowKind int
+
+const (
+	sequential flowKind = iota // continues with next instruction
+	branch                     // conditional branch to target
+	jump                       // unconditional jump to target
+	call                       // subroutine call to target, returning to next instruction
+	ret                        // return from subroutine
+	halt                       // stop execution
+)
+
+// Fields of a machine code word
+type op struct {
+	opcode  prog.Opcode
+	d, a, b uint
+	k       int
+}
+
+func decode(inst prog.Instruction) op {
+	code := inst.MachineCode()
+	return op{
+		opcode: prog.Opcode(code / 1000),
+		d:      code / 100 % 10,
+		a:      code / 10 % 10,
+		b:      code % 10,
+		k:      inst.Constant(),
+	}
+}
+
+// How instruction at addr changes control flow, and where it goes
+func (o op) flow(addr uint) (flowKind, uint) {
+	switch o.opcode {
+	case prog.BEQ, prog.BGT:
+		dest := int(addr) + o.k
+		switch {
+		case o.k == 0 || dest < 0:
+			return halt, 0
+		case o.d == o.a && o.opcode == prog.BEQ:
+			return jump, uint(dest)
+		case o.d == o.a:
+			// a register is never greater than itself
+			return sequential, 0
+		}
+		return branch, uint(dest)
+	case prog.JMP:
+		switch {
+		case o.d == 0:
+			return jump, uint(o.k)
+		case o.k == 0:
+			// jumping to a return address held in a register
+			return ret, 0
+		}
+		return call, uint(o.k)
+	}
+	return sequential, 0
+}
+
+// A loop found in the control flow graph
+type loop struct {
+	head, exit uint
+	doWhile    bool // continue would skip the condition at the end
+}
+
+// A line of pseudo-code belonging to instruction at addr
+type line struct {
+	addr  int // -1 for lines such as closing braces
+	depth int
+	text  string
+}
+
+type decompiler struct {
+	program *prog.Program
+	ops     []op
+	isCode  []bool
+	names   map[uint]string // addresses named by symbols
+	funcs   map[uint]string // function names of call destinations
+	loopEnd map[uint]uint   // loop head to address of last branch back to it
+	gotos   map[uint]bool   // destinations of goto statements
+	lines   []line
+}
+
+func newDecompiler(program *prog.Program) *decompiler {
+	n := uint(len(program.Instructions))
+	dc := decompiler{
+		program: program,
+		ops:     make([]op, n),
+		isCode:  make([]bool, n),
+		names:   make(map[uint]string),
+		funcs:   make(map[uint]string),
+		loopEnd: make(map[uint]uint),
+		gotos:   make(map[uint]bool),
+	}
+
+	for label, addr := range program.Labels {
+		if addr >= n || strings.HasPrefix(label, ".") || syntheticLabel.MatchString(label) {
+			continue
+		}
+		// pick the same label every time when several name the same address
+		if name, ok := dc.names[addr]; !ok || label < name {
+			dc.names[addr] = label
+		}
+	}
+
+	for addr, inst := range program.Instructions {
+		if inst.PseudoCode() == prog.DAT {
+			continue
+		}
+		dc.isCode[addr] = true
+		dc.ops[addr] = decode(inst)
+	}
+
+	for addr := uint(0); addr < n; addr++ {
+		if !dc.isCode[addr] {
+			continue
+		}
+		kind, dest := dc.ops[addr].flow(addr)
+		switch kind {
+		case call:
+			if dest < n {
+				dc.funcs[dest] = dc.functionName(dest)
+			}
+		case branch, jump:
+			if end, ok := dc.loopEnd[dest]; dest <= addr && (!ok || addr > end) {
+				dc.loopEnd[dest] = addr
+			}
+		}
+	}
+	return &dc
+}
+
+func (dc *decompiler) functionName(addr uint) string {
+	if name, ok := dc.names[addr]; ok {
+		return name
+	}
+	return fmt.Sprintf("sub_%02d", addr)
+}
+
+// Name used by goto statements for addr
+func (dc *decompiler) labelName(addr uint) string {
+	if name, ok := dc.names[addr]; ok {
+		return name
+	}
+	return fmt.Sprintf("L%02d", addr)
+}
+
+// Last address reached from start without following calls or returns
+func (dc *decompiler) extent(start uint) uint {
+	n := uint(len(dc.ops))
+	end := start
+	visited := make(map[uint]bool)
+	work := []uint{start}
+	for len(work) > 0 {
+		addr := work[len(work)-1]
+		work = work[:len(work)-1]
+		if addr >= n || !dc.isCode[addr] || visited[addr] {
+			continue
+		}
+		visited[addr] = true
+		if addr > end {
+			end = addr
+		}
+
+		kind, dest := dc.ops[addr].flow(addr)
+		switch kind {
+		case sequential, call:
+			work = append(work, addr+1)
+		case branch:
+			work = append(work, addr+1, dest)
+		case jump:
+			work = append(work, dest)
+		}
+	}
+	return end
+}
+
+func (dc *decompiler) emit(addr int, depth int, format string, args ...any) {
+	dc.lines = append(dc.lines, line{addr, depth, fmt.Sprintf(format, args...)})
+}
+
+// Write pseudo-code for function starting at start
+func (dc *decompiler) function(name string, start uint) {
+	if start >= uint(len(dc.ops)) || !dc.isCode[start] {
+		return
+	}
+	if len(dc.lines) > 0 {
+		dc.emit(-1, 0, "")
+	}
+	dc.emit(-1, 0, "func %s() {", name)
+	dc.block(start, dc.extent(start)+1, nil, 1)
+	dc.emit(-1, 0, "}")
+}
+
+// Write pseudo-code for instructions from address from up to, but not including, to
+func (dc *decompiler) block(from, to uint, enclosing *loop, depth int) {
+	for addr := from; addr < to; {
+		if !dc.isCode[addr] {
+			addr++
+			continue
+		}
+		if end, ok := dc.loopEnd[addr]; ok && end < to && (enclosing == nil || enclosing.head != addr) {
+			dc.loop(addr, end, depth)
+			addr = end + 1
+			continue
+		}
+
+		o := dc.ops[addr]
+		kind, dest := o.flow(addr)
+		switch kind {
+		case sequential:
+			dc.statement(addr, depth)
+		case call:
+			dc.emit(int(addr), depth, "%s()", dc.funcs[dest])
+		case ret:
+			dc.emit(int(addr), depth, "return")
+		case halt:
+			dc.emit(int(addr), depth, "halt")
+		case jump:
+			if dest == dc.nextCode(addr) {
+				dc.emit(int(addr), depth, "")
+			} else {
+				dc.emit(int(addr), depth, "%s", dc.jump(dest, enclosing))
+			}
+		case branch:
+			switch {
+			case dest == dc.nextCode(addr):
+				dc.emit(int(addr), depth, "")
+			case dest > addr+1 && dest <= to && dc.loopExit(dest, enclosing) == "":
+				addr = dc.ifElse(addr, dest, to, enclosing, depth)
+				continue
+			default:
+				dc.emit(int(addr), depth, "if %s {", condition(o, false))
+				dc.emit(-1, depth+1, "%s", dc.jump(dest, enclosing))
+				dc.emit(-1, depth, "}")
+			}
+		}
+		addr++
+	}
+}
+
+// First address holding code after addr. Jumping there, such as when jumping
+// over data, is the same as continuing with the next instruction
+func (dc *decompiler) nextCode(addr uint) uint {
+	next := addr + 1
+	for next < uint(len(dc.isCode)) && !dc.isCode[next] {
+		next++
+	}
+	return next
+}
+
+// Statement leaving enclosing loop by jumping to dest, or empty if it doesn't
+func (dc *decompiler) loopExit(dest uint, enclosing *loop) string {
+	switch {
+	case enclosing == nil:
+		return ""
+	case dest == enclosing.exit:
+		return "break"
+	case dest == enclosing.head && !enclosing.doWhile:
+		return "continue"
+	}
+	return ""
+}
+
+// Statement performing a jump to dest
+func (dc *decompiler) jump(dest uint, enclosing *loop) string {
+	if stmt := dc.loopExit(dest, enclosing); stmt != "" {
+		return stmt
+	}
+	dc.gotos[dest] = true
+	return "goto " + dc.labelName(dest)
+}
+
+// Write loop from head to end, where end branches back to head
+func (dc *decompiler) loop(head, end uint, depth int) {
+	tail := dc.ops[end]
+	kind, _ := tail.flow(end)
+	if kind == branch {
+		dc.emit(int(head), depth, "do {")
+		dc.block(head, end, &loop{head: head, exit: end + 1, doWhile: true}, depth+1)
+		dc.emit(int(end), depth, "} while %s", condition(tail, false))
+		return
+	}
+
+	// a loop testing its condition first exits by branching past its end
+	first := dc.ops[head]
+	if kind, dest := first.flow(head); kind == branch && dest == end+1 {
+		dc.emit(int(head), depth, "while %s {", condition(first, true))
+		dc.block(head+1, end, &loop{head: head, exit: end + 1}, depth+1)
+	} else {
+		dc.emit(int(head), depth, "while true {")
+		dc.block(head, end, &loop{head: head, exit: end + 1}, depth+1)
+	}
+	dc.emit(int(end), depth, "}")
+}
+
+// Write if statement for branch at addr skipping ahead to dest, with an else
+// part when the skipped instructions end by jumping past further instructions.
+// Returns address following the statement
+func (dc *decompiler) ifElse(addr, dest, to uint, enclosing *loop, depth int) uint {
+	dc.emit(int(addr), depth, "if %s {", condition(dc.ops[addr], true))
+
+	last := dest - 1
+	kind, after := dc.ops[last].flow(last)
+	if last > addr && dc.isCode[last] && kind == jump && after > dest && after <= to &&
+		dc.loopExit(after, enclosing) == "" {
+		dc.block(addr+1, last, enclosing, depth+1)
+		dc.emit(int(last), depth, "} else {")
+		dc.block(dest, after, enclosing, depth+1)
+		dc.emit(-1, depth, "}")
+		return after
+	}
+
+	dc.block(addr+1, dest, enclosing, depth+1)
+	dc.emit(-1, depth, "}")
+	return dest
+}
+
+// Write out lines, placing labels before the first line of each goto
+// destination. Empty lines which only mark an address are left out
+func (dc *decompiler) write(writer io.Writer) error {
+	labelled := make(map[uint]bool)
+	var builder strings.Builder
+	for _, ln := range dc.lines {
+		if ln.addr >= 0 && dc.gotos[uint(ln.addr)] && !labelled[uint(ln.addr)] {
+			labelled[uint(ln.addr)] = true
+			fmt.Fprintf(&builder, "%s%s:\n", indent(ln.depth-1), dc.labelName(uint(ln.addr)))
+		}
+		if ln.text == "" && ln.addr >= 0 {
+			continue
+		}
+		fmt.Fprintf(&builder, "%s%s\n", indent(ln.depth), ln.text)
+	}
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+func indent(depth int) string {
+	if depth < 0 {
+		depth = 0
+	}
+	return strings.Repeat("    ", depth)
+}
+
+// Write program as structured pseudo-code. The program should come from the
+// disassembler, so that words which are not reached from the entry point are
+// DAT directives. Symbols in the program name functions, goto labels and
+// memory addresses
+func Write(writer io.Writer, program *prog.Program) error {
+	dc := newDecompiler(program)
+	dc.function("main", program.Entry)
+
+	starts := make([]uint, 0, len(dc.funcs))
+	for start := range dc.funcs {
+		if start != program.Entry {
+			starts = append(starts, start)
+		}
+	}
+	slices.Sort(starts)
+	for _, start := range starts {
+		dc.function(dc.funcs[start], start)
+	}
+	return dc.write(writer)
+}
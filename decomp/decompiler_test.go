@@ -0,0 +1,171 @@
+package decomp
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ordovician/calcutron/asm"
+	"github.com/ordovician/calcutron/disasm"
+	"github.com/ordovician/calcutron/prog"
+)
+
+func ExampleWrite() {
+	// simplemult.machine
+	memory := []uint{5109, 5209, 1300, 1331, 2299, 9208, 7309, 8000, 0}
+	program, _ := disasm.DisassembleMemory(memory)
+	Write(os.Stdout, program)
+
+	// Output:
+	// func main() {
+	//     while true {
+	//         x1 = input()
+	//         x2 = input()
+	//         x3 = 0
+	//         do {
+	//             x3 = x3 + x1
+	//             x2 = x2 - 1
+	//         } while x2 > 0
+	//         output(x3)
+	//     }
+	// }
+}
+
+// Symbols from assembly code name functions after labels
+func ExampleWrite_subroutines() {
+	source, _ := asm.AssembleFile("../examples/factorial.ct33")
+	program, _ := disasm.DisassembleMemoryWithOptions(source.MachineCode(),
+		&disasm.Options{Symbols: source.Labels})
+	Write(os.Stdout, program)
+
+	// Output:
+	// func main() {
+	//     x1 = input()
+	//     x2 = x1
+	//     x2 = x2 - 1
+	//     do {
+	//         multiply()
+	//         x2 = x2 - 1
+	//     } while x2 > 0
+	//     output(x1)
+	//     halt
+	// }
+	//
+	// func multiply() {
+	//     x4 = 0
+	//     x7 = x2
+	//     do {
+	//         x3 = x2 % 10
+	//         x2 = x2 / 10
+	//         multdigit()
+	//         x1 = x1 * 10
+	//     } while x2 > 0
+	//     x1 = x4
+	//     x2 = x7
+	//     return
+	// }
+	//
+	// func multdigit() {
+	//     do {
+	//         if x3 == 0 {
+	//             break
+	//         }
+	//         x4 = x4 + x1
+	//         x3 = x3 - 1
+	//     } while x3 > 0
+	//     return
+	// }
+}
+
+// Every instruction reached from the entry point or a subroutine should be
+// accounted for in the pseudo-code
+func checkCoverage(t *testing.T, name string, program *prog.Program) {
+	t.Helper()
+	dc := newDecompiler(program)
+	dc.function("main", program.Entry)
+	for start, fn := range dc.funcs {
+		dc.function(fn, start)
+	}
+
+	shown := make(map[int]bool)
+	for _, ln := range dc.lines {
+		shown[ln.addr] = true
+	}
+
+	reached := []uint{program.Entry}
+	for start := range dc.funcs {
+		reached = append(reached, start)
+	}
+	for _, start := range reached {
+		for addr := start; addr <= dc.extent(start); addr++ {
+			if dc.isCode[addr] && !shown[int(addr)] {
+				t.Errorf("%s: instruction at address %d missing from pseudo-code", name, addr)
+			}
+		}
+	}
+}
+
+func TestDecompileExamples(t *testing.T) {
+	files, _ := filepath.Glob("../examples/*.machine")
+	if len(files) == 0 {
+		t.Fatal("no machine code examples found")
+	}
+	for _, file := range files {
+		program, err := disasm.DisassembleFile(file)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		checkCoverage(t, file, program)
+	}
+
+	files, _ = filepath.Glob("../examples/*.ct33")
+	for _, file := range files {
+		source, err := asm.AssembleFile(file)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		program, _ := disasm.DisassembleMemoryWithOptions(source.MachineCode(),
+			&disasm.Options{Symbols: source.Labels})
+		checkCoverage(t, file, program)
+
+		var buffer bytes.Buffer
+		if err := Write(&buffer, program); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if !strings.HasPrefix(buffer.String(), "func main() {") {
+			t.Errorf("%s: unexpected pseudo-code\n%s", file, buffer.String())
+		}
+	}
+}
+
+// Jumping into the middle of a loop cannot be expressed with loops and if
+// statements alone
+func TestDecompileGoto(t *testing.T) {
+	source := `
+    INP x1
+    BRA middle
+top:
+    OUT x1
+middle:
+    DEC x1
+    BGT x1, x0, top
+    HLT
+`
+	assembled, err := asm.Assemble(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, _ := disasm.DisassembleMemoryWithOptions(assembled.MachineCode(),
+		&disasm.Options{Symbols: assembled.Labels})
+
+	var buffer bytes.Buffer
+	Write(&buffer, program)
+	got := buffer.String()
+	for _, want := range []string{"goto middle", "middle:", "output(x1)", "x1 = x1 - 1", "while x1 > 0"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("pseudo-code lacks %q\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package decomp
+
+import (
+	"fmt"
+
+	"github.com/ordovician/calcutron/prog"
+)
+
+// Register used as an operand. x0 always holds zero
+func reg(r uint) string {
+	if r == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("x%d", r)
+}
+
+// Expression adding constant k to operand
+func offset(operand string, k int) string {
+	switch {
+	case k > 0:
+		return fmt.Sprintf("%s + %d", operand, k)
+	case k < 0:
+		return fmt.Sprintf("%s - %d", operand, -k)
+	}
+	return operand
+}
+
+// Condition under which branch o is taken, or not taken when negate is set
+func condition(o op, negate bool) string {
+	left, right := reg(o.d), reg(o.a)
+	relation := map[prog.Opcode]string{prog.BEQ: "==", prog.BGT: ">"}[o.opcode]
+	if negate {
+		relation = map[string]string{"==": "!=", ">": "<="}[relation]
+	}
+
+	// x2 < 0 reads better than 0 > x2
+	if left == "0" && right != "0" {
+		left, right = right, left
+		relation = map[string]string{"==": "==", "!=": "!=", ">": "<", "<=": ">="}[relation]
+	}
+	return fmt.Sprintf("%s %s %s", left, relation, right)
+}
+
+// Memory location accessed by LOAD or STOR, named after a label when possible
+func (dc *decompiler) location(o op) string {
+	if o.a != 0 {
+		return fmt.Sprintf("mem[%s]", offset(reg(o.a), o.k))
+	}
+	if name, ok := dc.names[uint(o.k)]; ok && o.k >= 0 && !dc.isCode[o.k] {
+		return name
+	}
+	return fmt.Sprintf("mem[%d]", o.k)
+}
+
+// Write statement for instruction at addr which doesn't change control flow.
+// Instructions without any effect only mark their address
+func (dc *decompiler) statement(addr uint, depth int) {
+	o := dc.ops[addr]
+	assign := func(format string, args ...any) {
+		dc.emit(int(addr), depth, "%s = %s", reg(o.d), fmt.Sprintf(format, args...))
+	}
+
+	switch o.opcode {
+	case prog.ADD, prog.SUB:
+		operator := map[prog.Opcode]string{prog.ADD: "+", prog.SUB: "-"}[o.opcode]
+		switch {
+		case o.d == 0:
+			dc.emit(int(addr), depth, "")
+		case o.a == 0 && o.b == 0:
+			if dc.clearedForCall(addr) {
+				dc.emit(int(addr), depth, "")
+			} else {
+				assign("0")
+			}
+		case o.b == 0:
+			assign("%s", reg(o.a))
+		case o.a == 0 && o.opcode == prog.ADD:
+			assign("%s", reg(o.b))
+		case o.a == 0:
+			assign("-%s", reg(o.b))
+		default:
+			assign("%s %s %s", reg(o.a), operator, reg(o.b))
+		}
+	case prog.ADDI:
+		if o.d == 0 {
+			dc.emit(int(addr), depth, "")
+		} else {
+			assign("%s", offset(reg(o.d), o.k))
+		}
+	case prog.LODI:
+		if o.d == 0 {
+			dc.emit(int(addr), depth, "")
+		} else if name, ok := dc.names[uint(o.k)]; ok && o.k >= 0 && !dc.isCode[o.k] {
+			assign("&%s", name)
+		} else {
+			assign("%d", o.k)
+		}
+	case prog.LSH:
+		dc.shift(addr, depth)
+	case prog.LOAD:
+		switch {
+		case o.a == 0 && o.k == -1 && o.d == 0:
+			dc.emit(int(addr), depth, "input()")
+		case o.a == 0 && o.k == -1:
+			assign("input()")
+		case o.d == 0:
+			dc.emit(int(addr), depth, "")
+		default:
+			assign("%s", dc.location(o))
+		}
+	case prog.STOR:
+		if o.a == 0 && o.k == -1 {
+			dc.emit(int(addr), depth, "output(%s)", reg(o.d))
+		} else {
+			dc.emit(int(addr), depth, "%s = %s", dc.location(o), reg(o.d))
+		}
+	default:
+		dc.emit(int(addr), depth, "")
+	}
+}
+
+// Write shift at addr as arithmetic. Digits shifted out of xa end up in xd
+func (dc *decompiler) shift(addr uint, depth int) {
+	o := dc.ops[addr]
+	if o.a == 0 {
+		if o.d != 0 {
+			dc.emit(int(addr), depth, "%s = 0", reg(o.d))
+		} else {
+			dc.emit(int(addr), depth, "")
+		}
+		return
+	}
+
+	multiplier := 1
+	for i := 0; i < o.k || i < -o.k; i++ {
+		multiplier *= 10
+	}
+
+	ra, rd := reg(o.a), reg(o.d)
+	if o.k >= 0 {
+		if o.d != 0 && o.d != o.a {
+			dc.emit(int(addr), depth, "%s = %s * %d / 10000", rd, ra, multiplier)
+		}
+		dc.emit(int(addr), depth, "%s = %s * %d", ra, ra, multiplier)
+	} else {
+		if o.d != 0 && o.d != o.a {
+			dc.emit(int(addr), depth, "%s = %s %% %d", rd, ra, multiplier)
+		}
+		dc.emit(int(addr), depth, "%s = %s / %d", ra, ra, multiplier)
+	}
+}
+
+// Whether instruction at addr only clears the register used by a subroutine
+// call right after it, as in CLR x9 followed by JMP x9, multiply
+func (dc *decompiler) clearedForCall(addr uint) bool {
+	next := addr + 1
+	if next >= uint(len(dc.ops)) || !dc.isCode[next] {
+		return false
+	}
+	kind, _ := dc.ops[next].flow(next)
+	return kind == call && dc.ops[next].d == dc.ops[addr].d
+}
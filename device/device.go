@@ -0,0 +1,123 @@
+// Package device contains peripherals which can be attached to the memory
+// of a simulated Calcutron-33 computer, in addition to the input and output
+// tape every computer has at address -1.
+//
+// Devices are given on the command line as name@address followed by options
+// separated by colons, such as random@9990:seed=7. Programs use a device by
+// reading and writing the addresses it claims with LOAD and STOR.
+package device
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ordovician/calcutron/sim"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// Options given after the address of a device, by name
+type Options map[string]string
+
+// Number given for option, or value if it is not given
+func (options Options) Int(name string, value int) (int, error) {
+	s, ok := options[name]
+	if !ok {
+		return value, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("option %s must be a number, not '%s'", name, s)
+	}
+	return n, nil
+}
+
+// Fail if any option is not one of names
+func (options Options) Check(names ...string) error {
+	for name := range options {
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("unknown option '%s'", name)
+		}
+	}
+	return nil
+}
+
+// Creates a device for comp from its options
+type Factory func(comp *sim.Computer, options Options) (sim.Device, error)
+
+var factories = map[string]Factory{
+	"disk":       newDisk,
+	"interrupts": newInterruptController,
+	"keyboard":   newKeyboard,
+	"random":     newRandom,
+	"screen":     newScreen,
+	"timer":      newTimer,
+}
+
+// InterruptController has to be created by the simulator, since it changes
+// the state of the processor
+func newInterruptController(comp *sim.Computer, options Options) (sim.Device, error) {
+	if err := options.Check(); err != nil {
+		return nil, err
+	}
+	return sim.NewInterruptController(comp), nil
+}
+
+// Make device available under name, so it can be given on the command line
+func Register(name string, factory Factory) {
+	factories[name] = factory
+}
+
+// Names of all devices which can be created
+func Names() []string {
+	names := maps.Keys(factories)
+	slices.Sort(names)
+	return names
+}
+
+// Create device for comp from name@address:option=value:... returning it
+// along with the address it should be attached at
+func Parse(comp *sim.Computer, spec string) (sim.Device, uint, error) {
+	fields := strings.Split(spec, ":")
+	name, at, found := strings.Cut(strings.TrimSpace(fields[0]), "@")
+	if !found {
+		return nil, 0, fmt.Errorf("device '%s' must be given as name@address", spec)
+	}
+
+	factory, ok := factories[name]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown device '%s'. Use one of %s", name, strings.Join(Names(), ", "))
+	}
+
+	address, err := strconv.Atoi(at)
+	if err != nil || address < 0 || address >= sim.MemorySize {
+		return nil, 0, fmt.Errorf("%s device address must be between 0 and %d, not '%s'", name, sim.MemorySize-1, at)
+	}
+
+	options := make(Options)
+	for _, field := range fields[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		options[key] = value
+	}
+
+	dev, err := factory(comp, options)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot create %s device because %w", name, err)
+	}
+	return dev, uint(address), nil
+}
+
+// Create devices from specs and attach them to comp
+func Attach(comp *sim.Computer, specs []string) error {
+	for _, spec := range specs {
+		dev, address, err := Parse(comp, spec)
+		if err != nil {
+			return err
+		}
+		if err := comp.AttachDevice(address, dev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
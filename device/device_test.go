@@ -0,0 +1,237 @@
+package device
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ordovician/calcutron/asm"
+	"github.com/ordovician/calcutron/sim"
+	"golang.org/x/exp/slices"
+)
+
+func TestParse(t *testing.T) {
+	dev, address, err := Parse(&sim.Computer{}, "random@9990:seed=7")
+	if err != nil || dev.Name() != "random" || address != 9990 {
+		t.Errorf("expected random device at 9990, got %v at %d and error %v", dev, address, err)
+	}
+
+	for _, spec := range []string{"random", "random@10000", "random@here", "printer@10", "random@10:seed=x", "random@10:colour=red"} {
+		if _, _, err := Parse(&sim.Computer{}, spec); err == nil {
+			t.Errorf("expected '%s' to be an error", spec)
+		}
+	}
+}
+
+func TestRandom(t *testing.T) {
+	source := "LODI x9, -10\nLOAD x1, x9\nOUT x1\nLOAD x1, x9\nOUT x1\nHLT"
+	program, err := asm.Assemble(bytes.NewReader([]byte(source)))
+	if err != nil {
+		t.Fatalf("failed to assemble because %v", err)
+	}
+	comp, _ := sim.NewComputer(program)
+	if err := Attach(comp, []string{"random@9990:seed=3"}); err != nil {
+		t.Fatalf("failed to attach device because %v", err)
+	}
+
+	comp.Run(10)
+	first := slices.Clone(comp.Outputs())
+	if len(first) != 2 || first[0] > maxRandom || first[1] > maxRandom {
+		t.Fatalf("expected two random numbers from 0 to %d, got %v", maxRandom, first)
+	}
+
+	comp.Reset()
+	comp.Run(10)
+	if !slices.Equal(first, comp.Outputs()) {
+		t.Errorf("expected same numbers %v after reset, got %v", first, comp.Outputs())
+	}
+}
+
+func TestTimer(t *testing.T) {
+	comp, err := sim.NewComputerFile("../examples/ticker.ct33")
+	if err != nil {
+		t.Fatalf("failed to load program because %v", err)
+	}
+	if err := Attach(comp, []string{"interrupts@9990", "timer@9993:every=10"}); err != nil {
+		t.Fatalf("failed to attach devices because %v", err)
+	}
+
+	// main loop counts while handler outputs count every 10 instructions
+	result := comp.RunLimited(sim.DefaultLimits, nil)
+	expected := []uint{2, 5, 7}
+	if result.Reason != sim.Halted || !slices.Equal(comp.Outputs(), expected) {
+		t.Errorf("expected outputs %v after halting, got %v and %v", expected, comp.Outputs(), result.Err())
+	}
+
+	// stopped timer never interrupts the loop
+	comp, _ = sim.NewComputerFile("../examples/ticker.ct33")
+	if err := Attach(comp, []string{"interrupts@9990", "timer@9993"}); err != nil {
+		t.Fatalf("failed to attach devices because %v", err)
+	}
+	if result := comp.RunLimited(sim.DefaultLimits, nil); result.Reason != sim.StepLimit || len(comp.Outputs()) != 0 {
+		t.Errorf("expected program with stopped timer to loop until step limit, got %v", result.Err())
+	}
+}
+
+func TestScreen(t *testing.T) {
+	comp, err := sim.NewComputerFile("../examples/diagonal.ct33")
+	if err != nil {
+		t.Fatalf("failed to load program because %v", err)
+	}
+	screen := NewScreen(DefaultScreenWidth, DefaultScreenHeight)
+	if err := comp.AttachDevice(9898, screen); err != nil {
+		t.Fatalf("failed to attach screen because %v", err)
+	}
+	var buffer bytes.Buffer
+	display := NewDisplay(&buffer, screen)
+	display.Draw()
+	comp.Run(100)
+	display.Draw()
+
+	var text bytes.Buffer
+	screen.WriteText(&text)
+	expected := "*\n *\n  *\n   *\n    *\n     *\n      *\n       *\n        *\n         *\n"
+	if text.String() != expected {
+		t.Errorf("expected diagonal line\n%s\ngot\n%s", expected, text.String())
+	}
+
+	// display draws over the frame it drew before
+	if !bytes.Contains(buffer.Bytes(), []byte("\x1b[12A┌──────────┐\n│*         │\n")) {
+		t.Errorf("expected display to redraw screen in place, got\n%s", buffer.String())
+	}
+}
+
+func TestKeyboard(t *testing.T) {
+	comp, err := sim.NewComputerFile("../examples/typewriter.ct33")
+	if err != nil {
+		t.Fatalf("failed to load program because %v", err)
+	}
+	if err := Attach(comp, []string{"keyboard@9890:keys=hi", "screen@9898"}); err != nil {
+		t.Fatalf("failed to attach devices because %v", err)
+	}
+	screen := comp.Devices()[2].Device.(*Screen)
+	kb := comp.Devices()[1].Device.(*Keyboard)
+
+	// program waits for more keys after showing the ones it started with
+	comp.Run(100)
+	if screen.Row(0) != "hi" || comp.PC() != 6 {
+		t.Errorf("expected program to show 'hi' and wait for keys, got '%s' at %d", screen.Row(0), comp.PC())
+	}
+
+	kb.Press('!')
+	kb.Press('\n')
+	comp.Step()
+	if pending := comp.PendingInterrupts(); len(pending) != 1 || pending[0] != "keyboard" {
+		t.Errorf("expected keys to raise keyboard interrupt, got %v", pending)
+	}
+	if result := comp.Run(100); result.Reason != sim.Halted || screen.Row(0) != "hi!" {
+		t.Errorf("expected program to halt on Enter showing 'hi!', got '%s' and %v", screen.Row(0), result.Err())
+	}
+
+	// Enter can be given as an escape in keys option
+	comp, _ = sim.NewComputerFile("../examples/typewriter.ct33")
+	if err := Attach(comp, []string{`keyboard@9890:keys=a\\b\n`, "screen@9898"}); err != nil {
+		t.Fatalf("failed to attach devices because %v", err)
+	}
+	screen = comp.Devices()[2].Device.(*Screen)
+	if result := comp.Run(100); result.Reason != sim.Halted || screen.Row(0) != `a\b` {
+		t.Errorf("expected program to halt on Enter showing 'a\\b', got '%s' and %v", screen.Row(0), result.Err())
+	}
+	for _, keys := range []string{`a\`, `\q`} {
+		if _, _, err := Parse(comp, "keyboard@10:keys="+keys); err == nil {
+			t.Errorf("expected keys '%s' to be an error", keys)
+		}
+	}
+}
+
+func TestDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "count.disk")
+	spec := "disk@9980:file=" + path + ":blocks=2:size=4"
+
+	// count is kept on disk between runs
+	for run := uint(1); run <= 2; run++ {
+		comp, err := sim.NewComputerFile("../examples/runcounter.ct33")
+		if err != nil {
+			t.Fatalf("failed to load program because %v", err)
+		}
+		if err := Attach(comp, []string{spec}); err != nil {
+			t.Fatalf("failed to attach disk because %v", err)
+		}
+		comp.Run(100)
+		if expected := []uint{run, DiskOK}; !slices.Equal(comp.Outputs(), expected) {
+			t.Errorf("expected outputs %v on run %d, got %v", expected, run, comp.Outputs())
+		}
+	}
+
+	image, _ := os.ReadFile(path)
+	if expected := "0002 0000 0000 0000\n0000 0000 0000 0000\n"; string(image) != expected {
+		t.Errorf("expected disk image\n%s\ngot\n%s", expected, image)
+	}
+
+	disk, _ := NewDisk(path, 2, 4)
+	disk.Write(1, 2)
+	disk.Write(0, DiskRead)
+	if status, _ := disk.Read(0); status != DiskError {
+		t.Errorf("expected reading block outside disk to fail")
+	}
+
+	os.WriteFile(path, []byte("0001 12\n"), 0644)
+	if _, err := NewDisk(path, 2, 4); err == nil {
+		t.Errorf("expected words which are not 4 digits to be an error")
+	}
+}
+
+func TestStepBackDevices(t *testing.T) {
+	run := func(back int) *sim.Computer {
+		comp, err := sim.NewComputerFile("../examples/ticker.ct33")
+		if err != nil {
+			t.Fatalf("failed to load program because %v", err)
+		}
+		comp.HistorySize = 100
+		if err := Attach(comp, []string{"interrupts@9990", "timer@9993:every=10", "random@9980"}); err != nil {
+			t.Fatalf("failed to attach devices because %v", err)
+		}
+		comp.Run(15)
+		for i := 0; i < back; i++ {
+			comp.StepBack()
+		}
+		for i := 0; i < back; i++ {
+			comp.Step()
+		}
+		return comp
+	}
+
+	// timer fires at the same instruction after stepping back and running forward again
+	original, replayed := run(0), run(12)
+	if original.PC() != replayed.PC() || !slices.Equal(original.Outputs(), replayed.Outputs()) {
+		t.Errorf("expected replay to get to %d with outputs %v, got %d and %v",
+			original.PC(), original.Outputs(), replayed.PC(), replayed.Outputs())
+	}
+	if original.Devices()[2].Device.(*Timer).left != replayed.Devices()[2].Device.(*Timer).left {
+		t.Errorf("expected timer to count down the same after replay")
+	}
+
+	// random numbers read are given again
+	random := NewRandom(5)
+	first, _ := random.Read(0)
+	state := random.SaveState()
+	second, _ := random.Read(0)
+	random.RestoreState(state)
+	if again, _ := random.Read(0); again != second || first == second {
+		t.Errorf("expected %d again after restoring random device, got %d", second, again)
+	}
+
+	// keys passed on to program are pressed again
+	var comp sim.Computer
+	kb := NewKeyboard(&comp, "a")
+	state = kb.SaveState()
+	kb.Press('b')
+	kb.Tick()
+	kb.Read(1)
+	kb.RestoreState(state)
+	kb.Tick()
+	if n, _ := kb.Read(0); n != 2 {
+		t.Errorf("expected both keys to be waiting after restoring keyboard, got %d", n)
+	}
+}
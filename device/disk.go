@@ -0,0 +1,244 @@
+package device
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ordovician/calcutron/sim"
+	"golang.org/x/exp/slices"
+)
+
+// Size of disk unless given with blocks and size options
+const (
+	DefaultDiskBlocks    = 10
+	DefaultDiskBlockSize = 10
+)
+
+// Commands written to command register of disk
+const (
+	DiskRead  = 1 // read block into buffer
+	DiskWrite = 2 // write buffer to block
+)
+
+// Status read from command register of disk
+const (
+	DiskOK    = 0
+	DiskError = 1 // block number out of range, unknown command or file could not be written
+)
+
+// Disk stores blocks of words in a file, so programs can keep data between
+// runs and work on more data than fits in memory. Words are moved between
+// the disk and a buffer holding one block. It claims three words:
+//
+//	0  command: write 1 to read block into buffer and 2 to write buffer to
+//	   block. Reading gives 0 if the last command succeeded and 1 if it failed
+//	1  block: number of block to read or write
+//	2  data: next word of buffer, moving on to the following word every
+//	   time it is read or written
+//
+// Giving a command or setting the block number starts over from the first
+// word of the buffer. The file is plain text with the words of a block on
+// each line, and is written every time a block is written
+type Disk struct {
+	path      string
+	blockSize int
+	blocks    [][]uint
+
+	status   uint
+	block    uint
+	buffer   []uint
+	position int
+}
+
+// Create disk of blocks stored in file at path, which is read if it exists
+func NewDisk(path string, blocks, blockSize int) (*Disk, error) {
+	disk := Disk{path: path, blockSize: blockSize, buffer: make([]uint, blockSize)}
+	disk.blocks = make([][]uint, blocks)
+	for i := range disk.blocks {
+		disk.blocks[i] = make([]uint, blockSize)
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &disk, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not open disk image: %w", err)
+	}
+	defer file.Close()
+
+	if err := disk.readImage(file); err != nil {
+		return nil, fmt.Errorf("could not read disk image %s: %w", path, err)
+	}
+	return &disk, nil
+}
+
+func newDisk(comp *sim.Computer, options Options) (sim.Device, error) {
+	if err := options.Check("file", "blocks", "size"); err != nil {
+		return nil, err
+	}
+	path := options["file"]
+	if path == "" {
+		return nil, fmt.Errorf("file option giving disk image is required")
+	}
+	blocks, err := options.Int("blocks", DefaultDiskBlocks)
+	if err != nil {
+		return nil, err
+	}
+	size, err := options.Int("size", DefaultDiskBlockSize)
+	if err != nil {
+		return nil, err
+	}
+	if blocks <= 0 || blocks >= 1e4 || size <= 0 || size >= 1e4 {
+		return nil, fmt.Errorf("number of blocks and block size must be between 1 and 9999")
+	}
+	return NewDisk(path, blocks, size)
+}
+
+// Read words from image, filling blocks in order. Words may be split into
+// lines any way, since line breaks are only there to make image readable
+func (disk *Disk) readImage(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Split(bufio.ScanWords)
+	i := 0
+	for ; scanner.Scan(); i++ {
+		word := scanner.Text()
+		value, err := strconv.Atoi(word)
+		if err != nil || len(word) != 4 || value < 0 {
+			return fmt.Errorf("'%s' is not a 4-digit word", word)
+		}
+		if i >= len(disk.blocks)*disk.blockSize {
+			return fmt.Errorf("image holds more than %d blocks of %d words", len(disk.blocks), disk.blockSize)
+		}
+		disk.blocks[i/disk.blockSize][i%disk.blockSize] = uint(value)
+	}
+	return scanner.Err()
+}
+
+// Write every block as a line of 4-digit words
+func (disk *Disk) WriteImage(writer io.Writer) error {
+	buffered := bufio.NewWriter(writer)
+	for _, block := range disk.blocks {
+		words := make([]string, len(block))
+		for i, word := range block {
+			words[i] = fmt.Sprintf("%04d", word)
+		}
+		fmt.Fprintln(buffered, strings.Join(words, " "))
+	}
+	return buffered.Flush()
+}
+
+func (disk *Disk) save() error {
+	file, err := os.Create(disk.path)
+	if err != nil {
+		return err
+	}
+	if err := disk.WriteImage(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (disk *Disk) Name() string {
+	return "disk"
+}
+
+func (disk *Disk) Size() uint {
+	return 3
+}
+
+func (disk *Disk) Read(offset uint) (uint, bool) {
+	switch offset {
+	case 0:
+		return disk.status, true
+	case 1:
+		return disk.block, true
+	}
+	value := disk.buffer[disk.position]
+	disk.position = (disk.position + 1) % disk.blockSize
+	return value, true
+}
+
+func (disk *Disk) Write(offset uint, value uint) {
+	switch offset {
+	case 0:
+		disk.command(value)
+	case 1:
+		disk.block = value
+		disk.position = 0
+	default:
+		disk.buffer[disk.position] = value
+		disk.position = (disk.position + 1) % disk.blockSize
+	}
+}
+
+func (disk *Disk) command(cmd uint) {
+	disk.position = 0
+	disk.status = DiskError
+	if disk.block >= uint(len(disk.blocks)) {
+		return
+	}
+
+	switch cmd {
+	case DiskRead:
+		copy(disk.buffer, disk.blocks[disk.block])
+	case DiskWrite:
+		copy(disk.blocks[disk.block], disk.buffer)
+		if disk.save() != nil {
+			return
+		}
+	default:
+		return
+	}
+	disk.status = DiskOK
+}
+
+type diskState struct {
+	status, block uint
+	buffer        []uint
+	position      int
+	data          []uint // contents of block, which a command may write
+}
+
+// Only the block being pointed to can change before the state is saved
+// again, since an instruction writes the disk at most once
+func (disk *Disk) SaveState() any {
+	state := diskState{
+		status:   disk.status,
+		block:    disk.block,
+		buffer:   slices.Clone(disk.buffer),
+		position: disk.position,
+	}
+	if disk.block < uint(len(disk.blocks)) {
+		state.data = slices.Clone(disk.blocks[disk.block])
+	}
+	return state
+}
+
+// Undoing a write to a block writes the file again with the old contents
+func (disk *Disk) RestoreState(state any) {
+	saved := state.(diskState)
+	disk.status = saved.status
+	disk.block = saved.block
+	copy(disk.buffer, saved.buffer)
+	disk.position = saved.position
+	if saved.data != nil && !slices.Equal(disk.blocks[saved.block], saved.data) {
+		copy(disk.blocks[saved.block], saved.data)
+		disk.save()
+	}
+}
+
+// Contents of disk stay the same, since they are meant to be kept between runs
+func (disk *Disk) Reset() {
+	disk.status = DiskOK
+	disk.block = 0
+	disk.position = 0
+	for i := range disk.buffer {
+		disk.buffer[i] = 0
+	}
+}
@@ -0,0 +1,155 @@
+package device
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ordovician/calcutron/sim"
+	"golang.org/x/exp/slices"
+)
+
+// Keyboard keeps keys pressed until the program reads them, and raises an
+// interrupt when keys arrive. It claims two words:
+//
+//	0  status: number of keys waiting to be read
+//	1  data: next key as a Unicode code point, or 0 if there are none
+//
+// Keys can be pressed from another goroutine while the program runs. They
+// reach the program after the instruction being executed, so it always sees
+// the same keys until it does something. The keys option gives keys which
+// are waiting when the program starts, for running without a terminal. It
+// may use \n for Enter, \t for Tab and \\ for a backslash
+type Keyboard struct {
+	comp      *sim.Computer
+	interrupt uint   // interrupt number raised
+	initial   []uint // keys waiting when computer is reset
+	keys      []uint // keys passed on to program since reset, in order they arrived
+	read      int    // number of keys read by program
+
+	mutex   sync.Mutex
+	pressed []uint // keys pressed but not yet passed on to buffer
+}
+
+func NewKeyboard(comp *sim.Computer, keys string) *Keyboard {
+	kb := Keyboard{
+		comp:      comp,
+		interrupt: comp.AddInterruptSource("keyboard"),
+	}
+	for _, r := range keys {
+		kb.initial = append(kb.initial, uint(r))
+	}
+	kb.Reset()
+	return &kb
+}
+
+func newKeyboard(comp *sim.Computer, options Options) (sim.Device, error) {
+	if err := options.Check("keys"); err != nil {
+		return nil, err
+	}
+	keys, err := unescapeKeys(options["keys"])
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyboard(comp, keys), nil
+}
+
+// Turn escapes such as \n into the keys they stand for, since keys given on
+// the command line cannot hold Enter
+func unescapeKeys(keys string) (string, error) {
+	var unescaped strings.Builder
+	escaped := false
+	for _, r := range keys {
+		if !escaped {
+			if r == '\\' {
+				escaped = true
+			} else {
+				unescaped.WriteRune(r)
+			}
+			continue
+		}
+
+		escaped = false
+		switch r {
+		case 'n':
+			unescaped.WriteRune('\n')
+		case 't':
+			unescaped.WriteRune('\t')
+		case '\\':
+			unescaped.WriteRune('\\')
+		default:
+			return "", fmt.Errorf("unknown escape '\\%c' in keys, use \\n, \\t or \\\\", r)
+		}
+	}
+	if escaped {
+		return "", fmt.Errorf("keys cannot end with a single backslash")
+	}
+	return unescaped.String(), nil
+}
+
+func (kb *Keyboard) Name() string {
+	return "keyboard"
+}
+
+func (kb *Keyboard) Size() uint {
+	return 2
+}
+
+func (kb *Keyboard) Read(offset uint) (uint, bool) {
+	if offset == 0 {
+		return uint(len(kb.keys) - kb.read), true
+	}
+	if kb.read == len(kb.keys) {
+		return 0, true
+	}
+	kb.read++
+	return kb.keys[kb.read-1], true
+}
+
+func (kb *Keyboard) Write(offset uint, value uint) {}
+
+// Press key, which may be done while the program is running
+func (kb *Keyboard) Press(key rune) {
+	if key < 0 || key >= sim.MemorySize {
+		return
+	}
+	kb.mutex.Lock()
+	kb.pressed = append(kb.pressed, uint(key))
+	kb.mutex.Unlock()
+}
+
+func (kb *Keyboard) Tick() {
+	kb.mutex.Lock()
+	pressed := kb.pressed
+	kb.pressed = nil
+	kb.mutex.Unlock()
+
+	if len(pressed) > 0 {
+		kb.keys = append(kb.keys, pressed...)
+		kb.comp.RaiseInterrupt(kb.interrupt)
+	}
+}
+
+type keyboardState struct {
+	keys, read int
+}
+
+func (kb *Keyboard) SaveState() any {
+	return keyboardState{len(kb.keys), kb.read}
+}
+
+// Keys which arrived after the state was saved go back to being pressed, so
+// they arrive again when the program runs forward
+func (kb *Keyboard) RestoreState(state any) {
+	saved := state.(keyboardState)
+	kb.mutex.Lock()
+	kb.pressed = append(slices.Clone(kb.keys[saved.keys:]), kb.pressed...)
+	kb.mutex.Unlock()
+	kb.keys = kb.keys[:saved.keys]
+	kb.read = saved.read
+}
+
+func (kb *Keyboard) Reset() {
+	kb.keys = slices.Clone(kb.initial)
+	kb.read = 0
+}
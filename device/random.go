@@ -0,0 +1,85 @@
+package device
+
+import (
+	"math/rand"
+
+	"github.com/ordovician/calcutron/sim"
+)
+
+// Largest number given by random device, which is the largest positive
+// number a register can hold
+const maxRandom = 4999
+
+// Random number generator. Reading gives a number from 0 to 4999 and writing
+// starts the sequence over from the seed written. Every run gives the same
+// numbers, unless another seed is given with the seed option
+type Random struct {
+	seed    int64 // seed given with option
+	current int64 // seed of sequence being read
+	draws   int   // numbers read since seeding
+	rng     *rand.Rand
+}
+
+func NewRandom(seed int64) *Random {
+	r := Random{seed: seed}
+	r.Reset()
+	return &r
+}
+
+func newRandom(comp *sim.Computer, options Options) (sim.Device, error) {
+	if err := options.Check("seed"); err != nil {
+		return nil, err
+	}
+	seed, err := options.Int("seed", 1)
+	if err != nil {
+		return nil, err
+	}
+	return NewRandom(int64(seed)), nil
+}
+
+func (r *Random) Name() string {
+	return "random"
+}
+
+func (r *Random) Size() uint {
+	return 1
+}
+
+func (r *Random) Read(offset uint) (uint, bool) {
+	r.draws++
+	return uint(r.rng.Intn(maxRandom + 1)), true
+}
+
+func (r *Random) Write(offset uint, value uint) {
+	r.start(int64(value), 0)
+}
+
+// Start sequence from seed, skipping the first draws numbers
+func (r *Random) start(seed int64, draws int) {
+	r.current = seed
+	r.draws = draws
+	r.rng = rand.New(rand.NewSource(seed))
+	for i := 0; i < draws; i++ {
+		r.rng.Intn(maxRandom + 1)
+	}
+}
+
+type randomState struct {
+	seed  int64
+	draws int
+}
+
+func (r *Random) SaveState() any {
+	return randomState{r.current, r.draws}
+}
+
+// The generator cannot go backwards, so it is started over and the numbers
+// already read are drawn again
+func (r *Random) RestoreState(state any) {
+	saved := state.(randomState)
+	r.start(saved.seed, saved.draws)
+}
+
+func (r *Random) Reset() {
+	r.start(r.seed, 0)
+}
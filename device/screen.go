@@ -0,0 +1,162 @@
+package device
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ordovician/calcutron/sim"
+	"golang.org/x/exp/slices"
+)
+
+// Size of screen unless given with width and height options
+const (
+	DefaultScreenWidth  = 10
+	DefaultScreenHeight = 10
+)
+
+// Screen is a grid of character cells, with one word for each cell row by
+// row starting at the top left corner. A cell shows the character with the
+// Unicode code point written to it, or nothing when it is 0
+type Screen struct {
+	Width    int
+	Height   int
+	cells    []uint
+	OnChange func(screen *Screen) // called after every write, unless nil
+}
+
+func NewScreen(width, height int) *Screen {
+	return &Screen{Width: width, Height: height, cells: make([]uint, width*height)}
+}
+
+func newScreen(comp *sim.Computer, options Options) (sim.Device, error) {
+	if err := options.Check("width", "height"); err != nil {
+		return nil, err
+	}
+	width, err := options.Int("width", DefaultScreenWidth)
+	if err != nil {
+		return nil, err
+	}
+	height, err := options.Int("height", DefaultScreenHeight)
+	if err != nil {
+		return nil, err
+	}
+	if width <= 0 || height <= 0 || width*height >= sim.MemorySize {
+		return nil, fmt.Errorf("screen of %d×%d cells does not fit in memory", width, height)
+	}
+	return NewScreen(width, height), nil
+}
+
+func (screen *Screen) Name() string {
+	return "screen"
+}
+
+func (screen *Screen) Size() uint {
+	return uint(len(screen.cells))
+}
+
+func (screen *Screen) Read(offset uint) (uint, bool) {
+	return screen.cells[offset], true
+}
+
+func (screen *Screen) Write(offset uint, value uint) {
+	screen.cells[offset] = value
+	if screen.OnChange != nil {
+		screen.OnChange(screen)
+	}
+}
+
+func (screen *Screen) Reset() {
+	for i := range screen.cells {
+		screen.cells[i] = 0
+	}
+}
+
+func (screen *Screen) SaveState() any {
+	return slices.Clone(screen.cells)
+}
+
+func (screen *Screen) RestoreState(state any) {
+	copy(screen.cells, state.([]uint))
+	if screen.OnChange != nil {
+		screen.OnChange(screen)
+	}
+}
+
+// Character shown in cell at column x and row y
+func (screen *Screen) Cell(x, y int) rune {
+	r := rune(screen.cells[y*screen.Width+x])
+	if !unicode.IsPrint(r) {
+		return ' '
+	}
+	return r
+}
+
+// Row y of screen, without trailing blanks
+func (screen *Screen) Row(y int) string {
+	var row strings.Builder
+	for x := 0; x < screen.Width; x++ {
+		row.WriteRune(screen.Cell(x, y))
+	}
+	return strings.TrimRight(row.String(), " ")
+}
+
+// Write every row of screen as a line of text
+func (screen *Screen) WriteText(writer io.Writer) {
+	for y := 0; y < screen.Height; y++ {
+		fmt.Fprintln(writer, screen.Row(y))
+	}
+}
+
+// Least time between redrawing live display, so programs filling the screen
+// are not slowed down by the terminal
+const redrawInterval = 20 * time.Millisecond
+
+// Display draws a screen inside a frame in a terminal, drawing it again in
+// the same place whenever it changes
+type Display struct {
+	screen *Screen
+	writer io.Writer
+	drawn  bool
+	last   time.Time
+}
+
+// Create display drawing screen to writer as it changes
+func NewDisplay(writer io.Writer, screen *Screen) *Display {
+	display := &Display{screen: screen, writer: writer}
+	screen.OnChange = func(*Screen) { display.update() }
+	return display
+}
+
+// Screen drawn by display
+func (display *Display) Screen() *Screen {
+	return display.screen
+}
+
+func (display *Display) update() {
+	if time.Since(display.last) >= redrawInterval {
+		display.Draw()
+	}
+}
+
+// Draw screen now, over the previous drawing
+func (display *Display) Draw() {
+	screen := display.screen
+	var frame strings.Builder
+	if display.drawn {
+		// move cursor back up to top of frame
+		fmt.Fprintf(&frame, "\x1b[%dA", screen.Height+2)
+	}
+	border := strings.Repeat("─", screen.Width)
+	fmt.Fprintf(&frame, "┌%s┐\n", border)
+	for y := 0; y < screen.Height; y++ {
+		fmt.Fprintf(&frame, "│%-*s│\n", screen.Width, screen.Row(y))
+	}
+	fmt.Fprintf(&frame, "└%s┘\n", border)
+
+	io.WriteString(display.writer, frame.String())
+	display.drawn = true
+	display.last = time.Now()
+}
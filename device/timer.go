@@ -0,0 +1,90 @@
+package device
+
+import (
+	"fmt"
+
+	"github.com/ordovician/calcutron/sim"
+)
+
+// Timer raises an interrupt every time a number of instructions have been
+// executed. Writing a number to it starts counting down from that number
+// over again, and writing 0 stops it. Reading gives the number of
+// instructions left until it fires. The every option sets the number of
+// instructions it starts with, otherwise it is stopped until written to
+type Timer struct {
+	comp      *sim.Computer
+	interrupt uint // interrupt number raised
+	initial   uint // period timer starts with when computer is reset
+	period    uint
+	left      uint
+}
+
+func NewTimer(comp *sim.Computer, every uint) *Timer {
+	timer := Timer{
+		comp:      comp,
+		interrupt: comp.AddInterruptSource("timer"),
+		initial:   every,
+	}
+	timer.Reset()
+	return &timer
+}
+
+func newTimer(comp *sim.Computer, options Options) (sim.Device, error) {
+	if err := options.Check("every"); err != nil {
+		return nil, err
+	}
+	every, err := options.Int("every", 0)
+	if err != nil {
+		return nil, err
+	}
+	if every < 0 || every >= 1e4 {
+		return nil, fmt.Errorf("every must be between 0 and 9999, not %d", every)
+	}
+	return NewTimer(comp, uint(every)), nil
+}
+
+func (timer *Timer) Name() string {
+	return "timer"
+}
+
+func (timer *Timer) Size() uint {
+	return 1
+}
+
+func (timer *Timer) Read(offset uint) (uint, bool) {
+	return timer.left, true
+}
+
+func (timer *Timer) Write(offset uint, value uint) {
+	timer.period = value
+	timer.left = value
+}
+
+func (timer *Timer) Tick() {
+	if timer.period == 0 {
+		return
+	}
+	timer.left--
+	if timer.left == 0 {
+		timer.comp.RaiseInterrupt(timer.interrupt)
+		timer.left = timer.period
+	}
+}
+
+type timerState struct {
+	period, left uint
+}
+
+func (timer *Timer) SaveState() any {
+	return timerState{timer.period, timer.left}
+}
+
+func (timer *Timer) RestoreState(state any) {
+	saved := state.(timerState)
+	timer.period, timer.left = saved.period, saved.left
+}
+
+func (timer *Timer) Reset() {
+	timer.period = timer.initial
+	timer.left = timer.initial
+}
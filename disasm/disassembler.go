@@ -5,9 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strconv"
 	"strings"
-	"unicode"
 
 	"github.com/ordovician/calcutron/prog"
 	"github.com/ordovician/calcutron/utils"
@@ -23,67 +21,145 @@ func DisassembleInstruction(machinecode uint) prog.Instruction {
 	return inst
 }
 
+// Turn a memory word into a DAT directive holding its signed value
+func DisassembleData(machinecode uint) prog.Instruction {
+	inst := prog.NewInstruction(prog.DAT)
+	inst.DecodeOperands(machinecode)
+	return inst
+}
+
 // Disassemble a machine code program read from reader
 func Disassemble(reader io.Reader) (*prog.Program, error) {
-	machineprogram := make([]uint, 0, 10)
-	builder := strings.Builder{}
-	bufReader := bufio.NewReader(reader)
-
-	for {
-		rune, _, err := bufReader.ReadRune()
+	return DisassembleWithOptions(reader, nil)
+}
 
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, fmt.Errorf("unable to disassemble file: %w", err)
-		}
+// Disassemble a machine code program read from reader, using options to
+// separate code from data. Nil options means starting at address 0 without hints
+func DisassembleWithOptions(reader io.Reader, options *Options) (*prog.Program, error) {
+	var opts Options
+	if options != nil {
+		opts = *options
+	}
 
-		if unicode.IsSpace(rune) {
-			continue
-		}
-		if !unicode.IsDigit(rune) {
-			return nil, fmt.Errorf("machine code must be all digits. Cannot disassemble '%s'", string(rune))
-		}
+	machineprogram, hdr, err := readMachineCode(reader, opts.Strict)
+	if err != nil {
+		return nil, err
+	}
 
-		builder.WriteRune(rune)
-		if builder.Len() < 4 {
-			continue
-		}
+	if !opts.EntrySet {
+		opts.Entry = hdr.entry
+	}
+	opts.Symbols = hdr.symbols
 
-		machinecode, err := strconv.Atoi(builder.String())
-		if err != nil {
-			return nil, fmt.Errorf("unable to disassemble because: %w", err)
-		}
-		machineprogram = append(machineprogram, uint(machinecode))
-		builder.Reset()
+	program, err := DisassembleMemoryWithOptions(machineprogram, &opts)
+	if err != nil {
+		return nil, err
 	}
-	return DisassembleMemory(machineprogram)
+	program.Name = hdr.name
+	return program, nil
 }
 
-// Disassemble a section of our made up computer memory
+// Disassemble a section of our made up computer memory. Words which cannot be
+// reached by following control flow from address 0 are shown as DAT directives.
+// Destinations of branches and jumps are given synthetic labels such as L05,
+// or loop_03 when reached by jumping backwards
 func DisassembleMemory(machineprogram []uint) (*prog.Program, error) {
+	return DisassembleMemoryWithOptions(machineprogram, nil)
+}
+
+// Disassemble memory using options to separate code from data
+func DisassembleMemoryWithOptions(machineprogram []uint, options *Options) (*prog.Program, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
 	labels := make(prog.SymbolTable)
 	labels.AddIOLabels() // so we got labels like input and output
+	for label, addr := range options.Symbols {
+		labels[label] = addr
+	}
 	program := prog.Program{
+		Entry:        options.Entry,
 		Labels:       labels,
 		Instructions: make([]prog.Instruction, 0, 10),
 	}
 
-	var addr uint = 0
-	for _, machinecode := range machineprogram {
-		instruction := DisassembleInstruction(uint(machinecode))
-
-		if instruction != nil {
-			program.Add(instruction)
-			addr++
+	kinds := classify(machineprogram, options)
+	for addr, machinecode := range machineprogram {
+		var instruction prog.Instruction
+		if kinds[addr] == Data {
+			instruction = DisassembleData(machinecode)
+		} else {
+			instruction = DisassembleInstruction(machinecode)
+			if !options.NoPseudo {
+				instruction = prog.RecognizePseudo(instruction)
+			}
 		}
+		program.Add(instruction)
 	}
+
+	labelDestinations(&program)
 	return &program, nil
 }
 
+// Give a label to every address within program which is the destination of a
+// branch or jump, unless it already has one, and make those instructions refer to it
+func labelDestinations(program *prog.Program) {
+	n := uint(len(program.Instructions))
+	backward := make(map[uint]bool)
+	for addr, inst := range program.Instructions {
+		if dest, ok := destination(inst, uint(addr)); ok && dest < n {
+			backward[dest] = backward[dest] || dest <= uint(addr)
+		}
+	}
+
+	// prefer symbols we already know about to synthetic labels
+	names := canonicalLabels(program.Labels, n)
+
+	for dest, isLoop := range backward {
+		if _, known := names[dest]; known {
+			continue
+		}
+		name := fmt.Sprintf("L%02d", dest)
+		if isLoop {
+			name = fmt.Sprintf("loop_%02d", dest)
+		}
+		names[dest] = name
+		program.Labels[name] = dest
+	}
+
+	for addr, inst := range program.Instructions {
+		if dest, ok := destination(inst, uint(addr)); ok {
+			if name, ok := names[dest]; ok {
+				inst.SetLabel(name)
+			}
+		}
+	}
+}
+
+// Name of label at each address below n. When several labels share an
+// address the smallest name is picked, so the same one is used every time.
+// Labels starting with a dot are offsets rather than addresses, and are left out
+func canonicalLabels(labels prog.SymbolTable, n uint) prog.AddressTable {
+	names := make(prog.AddressTable)
+	for label, addr := range labels {
+		if addr >= n || strings.HasPrefix(label, ".") {
+			continue
+		}
+		if other, ok := names[addr]; !ok || label < other {
+			names[addr] = label
+		}
+	}
+	return names
+}
+
 // DisassembleFile reads assembly code from file at path filepath and returns program representing code read
 func DisassembleFile(filepath string) (*prog.Program, error) {
+	return DisassembleFileWithOptions(filepath, nil)
+}
+
+// DisassembleFileWithOptions is like DisassembleFile but uses options to separate code from data
+func DisassembleFileWithOptions(filepath string, options *Options) (*prog.Program, error) {
 	file, err := os.Open(filepath)
 	if err != nil {
 		return nil, err
@@ -96,9 +172,10 @@ func DisassembleFile(filepath string) (*prog.Program, error) {
 	line = strings.TrimSpace(line)
 	file.Seek(0, io.SeekStart)
 
-	if !strings.HasSuffix(filepath, ".machine") && !utils.AllDigits(line) {
+	isHeader := strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//")
+	if !strings.HasSuffix(filepath, ".machine") && !utils.AllDigits(line) && !isHeader {
 		return nil, fmt.Errorf("file '%s' doesn't look like a machine code file.\nFirst line is '%s', which is not a number", filepath, line)
 	}
 
-	return Disassemble(file)
+	return DisassembleWithOptions(file, options)
 }
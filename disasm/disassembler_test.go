@@ -1 +1,246 @@
 package disasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ordovician/calcutron/asm"
+	"github.com/ordovician/calcutron/prog"
+)
+
+func Example_disassembleMemory() {
+	// simplemult.machine
+	memory := []uint{5109, 5209, 1300, 1331, 2299, 9208, 7309, 8000, 0}
+	program, _ := DisassembleMemory(memory)
+
+	program.PrintWithOptions(os.Stdout, &prog.PrintOptions{
+		Address:    true,
+		SourceCode: true,
+		Labels:     true,
+	})
+
+	// Output:
+	// loop_00:
+	// 00     INP  x1
+	// 01     INP  x2
+	// 02     CLR  x3
+	// loop_03:
+	// 03     ADD  x3, x3, x1
+	// 04     DEC  x2
+	// 05     BGT  x2, x0, loop_03
+	// 06     OUT  x3
+	// 07     JMP  x0, loop_00
+	// 08     DAT  0
+}
+
+func Example_disassembleMemoryWithOptions() {
+	// memadder-jmp.machine
+	memory := []uint{8004, 42, 23, 0, 5101, 5202, 1312, 7303, 0}
+
+	// treat final HLT as data even though execution reaches it
+	options := Options{Hints: map[uint]WordKind{8: Data}}
+	program, _ := DisassembleMemoryWithOptions(memory, &options)
+
+	program.PrintWithOptions(os.Stdout, &prog.PrintOptions{
+		Address:    true,
+		SourceCode: true,
+	})
+
+	// Output:
+	// 00     JMP  x0, L04
+	// 01     DAT  42
+	// 02     DAT  23
+	// 03     DAT  0
+	// 04     LOAD x1, x0, 1
+	// 05     LOAD x2, x0, 2
+	// 06     ADD  x3, x1, x2
+	// 07     STOR x3, x0, 3
+	// 08     DAT  0
+}
+
+// Disassemble machine code words into source code and assemble it again
+func roundTrip(t *testing.T, name string, words []uint, options *Options) {
+	program, err := DisassembleMemoryWithOptions(words, options)
+	if err != nil {
+		t.Errorf("unable to disassemble %s because %v", name, err)
+		return
+	}
+
+	var buffer bytes.Buffer
+	WriteSource(&buffer, program)
+	reassembled, err := asm.Assemble(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Errorf("unable to assemble disassembled %s because %v\n%s", name, err, buffer.String())
+		return
+	}
+
+	if len(reassembled.Instructions) != len(words) {
+		t.Errorf("%s has %d words but reassembled has %d", name, len(words), len(reassembled.Instructions))
+		return
+	}
+	for addr, inst := range reassembled.Instructions {
+		if inst.MachineCode() != words[addr] {
+			t.Errorf("%s at address %02d: expected %04d got %04d", name, addr, words[addr], inst.MachineCode())
+		}
+	}
+}
+
+func TestRoundTripExamples(t *testing.T) {
+	machineFiles, _ := filepath.Glob("../examples/*.machine")
+	for _, file := range machineFiles {
+		program, err := DisassembleFile(file)
+		if err != nil {
+			t.Errorf("unable to disassemble %s because %v", file, err)
+			continue
+		}
+		roundTrip(t, file, program.MachineCode(), nil)
+		roundTrip(t, file, program.MachineCode(), &Options{AllCode: true})
+		roundTrip(t, file, program.MachineCode(), &Options{NoPseudo: true})
+	}
+
+	sourceFiles, _ := filepath.Glob("../examples/*.ct33")
+	for _, file := range sourceFiles {
+		program, err := asm.AssembleFile(file)
+		if err != nil {
+			t.Errorf("unable to assemble %s because %v", file, err)
+			continue
+		}
+		roundTrip(t, file, program.MachineCode(), nil)
+	}
+
+	if len(machineFiles) == 0 || len(sourceFiles) == 0 {
+		t.Errorf("found no examples to round trip")
+	}
+}
+
+// Every possible word should survive being disassembled as an instruction
+func TestRoundTripAllWords(t *testing.T) {
+	for word := uint(0); word < 10000; word++ {
+		roundTrip(t, fmt.Sprintf("%04d", word), []uint{word}, &Options{AllCode: true})
+	}
+}
+
+func TestMachineFileHeader(t *testing.T) {
+	program, err := asm.AssembleFile("../examples/memcalc.ct33")
+	if err != nil {
+		t.Fatalf("unable to assemble memcalc.ct33 because %v", err)
+	}
+	program.Name = "memcalc"
+	program.Entry = program.Labels["start"]
+
+	var buffer bytes.Buffer
+	program.WriteMachineFile(&buffer)
+
+	loaded, err := Disassemble(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatalf("unable to disassemble machine file with header because %v\n%s", err, buffer.String())
+	}
+	if loaded.Name != "memcalc" || loaded.Entry != 6 || loaded.Labels["second"] != 4 {
+		t.Errorf("expected name memcalc, entry 6 and symbol second at 4 but got %s, %d and %d",
+			loaded.Name, loaded.Entry, loaded.Labels["second"])
+	}
+	if fmt.Sprint(loaded.MachineCode()) != fmt.Sprint(program.MachineCode()) {
+		t.Errorf("machine code changed when written with header")
+	}
+
+	// a changed word should be caught by checksum
+	corrupted := strings.Replace(buffer.String(), "1234", "1243", 1)
+	if _, err := Disassemble(strings.NewReader(corrupted)); err == nil {
+		t.Errorf("expected checksum mismatch to be reported")
+	}
+}
+
+func TestMachineFileComments(t *testing.T) {
+	src := `// adds two numbers
+#version 1
+#entry 1
+0000      // never run
+5109 5209 // read inputs
+1312
+7309`
+	program, err := Disassemble(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("unable to disassemble because %v", err)
+	}
+	if len(program.Instructions) != 5 || program.Entry != 1 {
+		t.Errorf("expected 5 instructions with entry 1 got %d with entry %d", len(program.Instructions), program.Entry)
+	}
+
+	// entry given explicitly is used even when it is 0
+	program, err = DisassembleWithOptions(strings.NewReader(src), &Options{EntrySet: true})
+	if err != nil || program.Entry != 0 {
+		t.Errorf("expected given entry 0 to override header, got entry %d", program.Entry)
+	}
+
+	if _, err := Disassemble(strings.NewReader("5109\n#entry 0\n")); err == nil {
+		t.Errorf("header after machine code should not be accepted")
+	}
+}
+
+func TestMachineCodeErrors(t *testing.T) {
+	src := `5109
+520
+9
+1312 73O9
+#entry 0`
+
+	_, err := Disassemble(strings.NewReader(src))
+	var errs SyntaxErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected syntax errors got %v", err)
+	}
+
+	expected := []string{
+		"line 2, column 1: '520' is not a 4-digit machine code word",
+		"line 3, column 1: '9' is not a 4-digit machine code word",
+		"line 4, column 6: '73O9' is not a 4-digit machine code word",
+		"line 5, column 1: header must come before machine code",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors got %d:\n%v", len(expected), len(errs), err)
+	}
+	for i, msg := range expected {
+		if errs[i].Error() != msg {
+			t.Errorf("expected '%s' got '%s'", msg, errs[i].Error())
+		}
+	}
+}
+
+func TestMachineCodeStrict(t *testing.T) {
+	src := "5109 5209\n1312\n"
+	if _, err := Disassemble(strings.NewReader(src)); err != nil {
+		t.Errorf("several words on a line should be accepted unless strict, got %v", err)
+	}
+
+	_, err := DisassembleWithOptions(strings.NewReader(src), &Options{Strict: true})
+	var errs SyntaxErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Line != 1 || errs[0].Column != 6 {
+		t.Errorf("expected error for second word on line 1 got %v", err)
+	}
+}
+
+func TestRoundTripSharedAddress(t *testing.T) {
+	program, err := asm.Assemble(strings.NewReader("start:\nloop:\n    INC  x1\n    BGT  x1, x0, loop\n    HLT\n"))
+	if err != nil {
+		t.Fatalf("unable to assemble because %v", err)
+	}
+	words := program.MachineCode()
+	options := &Options{Symbols: prog.SymbolTable{"start": 0, "loop": 0}}
+
+	// map order differs between runs, so try enough times to see any of them
+	for i := 0; i < 20; i++ {
+		roundTrip(t, "shared address", words, options)
+
+		disassembled, _ := DisassembleMemoryWithOptions(words, options)
+		var buffer bytes.Buffer
+		WriteSource(&buffer, disassembled)
+		if source := buffer.String(); !strings.HasPrefix(source, "loop:\n") || strings.Contains(source, "start") {
+			t.Fatalf("expected smallest label loop to name address 0, got\n%s", source)
+		}
+	}
+}
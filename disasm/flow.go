@@ -0,0 +1,126 @@
+package disasm
+
+import (
+	"github.com/ordovician/calcutron/prog"
+)
+
+// Kind of content stored in a memory word
+type WordKind int
+
+const (
+	Unknown WordKind = iota // decided by whether word is reached from entry
+	Code
+	Data
+)
+
+// Options controlling how memory is disassembled
+type Options struct {
+	Entry    uint              // address where execution starts
+	EntrySet bool              // Entry was given, rather than taken from machine code header
+	Hints    map[uint]WordKind // override classification of individual addresses
+	AllCode  bool              // treat every word as an instruction, skipping reachability analysis
+	NoPseudo bool              // show base instructions rather than recognizing pseudo instructions
+	Symbols  prog.SymbolTable  // known labels, used rather than synthetic ones
+	Strict   bool              // require exactly one word per line when reading machine code files
+}
+
+// Address an instruction at addr will jump to, if it can be determined
+// without running the program
+func destination(inst prog.Instruction, addr uint) (uint, bool) {
+	code := inst.MachineCode()
+	switch inst.PseudoCode() {
+	case prog.BEQ, prog.BGT, prog.BRA:
+		// a branch to itself is how we halt
+		if inst.Constant() == 0 {
+			return 0, false
+		}
+		dest := int(addr) + inst.Constant()
+		return uint(dest), dest >= 0
+	case prog.JMP, prog.CALL:
+		// a jump relative to a register other than x0 is only predictable
+		// when used as CALL, where we assume the register is cleared
+		rd := code / 100 % 10
+		if rd != 0 && inst.Constant() == 0 {
+			return 0, false
+		}
+		return uint(inst.Constant()), inst.Constant() >= 0
+	}
+	return 0, false
+}
+
+// Addresses execution may continue at after executing inst at addr
+func successors(inst prog.Instruction, addr uint) []uint {
+	code := inst.MachineCode()
+	rd, ra := code/100%10, code/10%10
+	next := make([]uint, 0, 2)
+
+	dest, known := destination(inst, addr)
+	switch inst.PseudoCode() {
+	case prog.BEQ:
+		if known {
+			next = append(next, dest)
+		}
+		// comparing a register with itself always branches
+		if inst.Constant() != 0 && rd != ra {
+			next = append(next, addr+1)
+		}
+	case prog.BGT:
+		if known && rd != ra {
+			next = append(next, dest)
+		}
+		if inst.Constant() != 0 {
+			next = append(next, addr+1)
+		}
+	case prog.JMP:
+		if known {
+			next = append(next, dest)
+		}
+		// a subroutine call returns to the following instruction, while
+		// jumping to an address held in a register is a return
+		if known && rd != 0 {
+			next = append(next, addr+1)
+		}
+	default:
+		next = append(next, addr+1)
+	}
+	return next
+}
+
+// Classify every word in memory as code or data by following control flow from
+// entry and from addresses hinted to be code
+func classify(memory []uint, options *Options) []WordKind {
+	kinds := make([]WordKind, len(memory))
+	if options.AllCode {
+		for addr := range kinds {
+			kinds[addr] = Code
+		}
+	}
+
+	work := []uint{options.Entry}
+	for addr, kind := range options.Hints {
+		if kind == Code {
+			work = append(work, addr)
+		}
+	}
+
+	for len(work) > 0 {
+		addr := work[len(work)-1]
+		work = work[:len(work)-1]
+		if addr >= uint(len(memory)) || kinds[addr] == Code || options.Hints[addr] == Data {
+			continue
+		}
+		kinds[addr] = Code
+
+		inst := DisassembleInstruction(memory[addr])
+		work = append(work, successors(inst, addr)...)
+	}
+
+	for addr := range kinds {
+		if hint := options.Hints[uint(addr)]; hint != Unknown {
+			kinds[addr] = hint
+		} else if kinds[addr] == Unknown {
+			kinds[addr] = Data
+		}
+	}
+	return kinds
+}
@@ -0,0 +1,182 @@
+package disasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ordovician/calcutron/prog"
+)
+
+// Information found in the optional header of a machine code file. See
+// prog.MachineFileVersion for a description of the format
+type header struct {
+	version     int
+	name        string
+	entry       uint
+	symbols     prog.SymbolTable
+	checksum    uint
+	hasChecksum bool
+}
+
+// Parse a header line such as '#entry 4'
+func (hdr *header) parseDirective(line string) error {
+	fields := strings.Fields(strings.TrimPrefix(line, "#"))
+	if len(fields) == 0 {
+		return fmt.Errorf("missing header directive after '#'")
+	}
+
+	directive, args := fields[0], fields[1:]
+	expectArgs := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("#%s expects %d arguments but got %d", directive, n, len(args))
+		}
+		return nil
+	}
+	number := func(s string) (uint, error) {
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("#%s expects a number not '%s'", directive, s)
+		}
+		return uint(n), nil
+	}
+
+	var err error
+	switch directive {
+	case "version":
+		var version uint
+		if err = expectArgs(1); err == nil {
+			version, err = number(args[0])
+			hdr.version = int(version)
+		}
+		if err == nil && hdr.version > prog.MachineFileVersion {
+			err = fmt.Errorf("machine code file format version %d is newer than supported version %d", hdr.version, prog.MachineFileVersion)
+		}
+	case "name":
+		hdr.name = strings.Join(args, " ")
+	case "entry":
+		if err = expectArgs(1); err == nil {
+			hdr.entry, err = number(args[0])
+		}
+	case "symbol":
+		var addr uint
+		if err = expectArgs(2); err == nil {
+			addr, err = number(args[1])
+			hdr.symbols[args[0]] = addr
+		}
+	case "checksum":
+		if err = expectArgs(1); err == nil {
+			hdr.checksum, err = number(args[0])
+			hdr.hasChecksum = true
+		}
+	default:
+		err = fmt.Errorf("unknown header directive '#%s'", directive)
+	}
+	return err
+}
+
+// A problem found at a particular place in a machine code file
+type SyntaxError struct {
+	Line   int    // line number, starting at 1
+	Column int    // column of offending text, starting at 1
+	Text   string // offending text
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// All problems found in a machine code file, so they can be fixed in one go
+type SyntaxErrors []*SyntaxError
+
+func (errs SyntaxErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Check if word is a 4-digit machine code word
+func isWord(word string) bool {
+	if len(word) != 4 {
+		return false
+	}
+	for _, ch := range word {
+		if !unicode.IsDigit(ch) {
+			return false
+		}
+	}
+	return true
+}
+
+// Read 4-digit machine code words and an optional header from reader. Words
+// are separated by whitespace and comments start with //. In strict mode each
+// line must hold exactly one word. Returns SyntaxErrors for every bad line
+func readMachineCode(reader io.Reader, strict bool) ([]uint, *header, error) {
+	machineprogram := make([]uint, 0, 10)
+	hdr := &header{symbols: make(prog.SymbolTable)}
+	errs := make(SyntaxErrors, 0)
+
+	scanner := bufio.NewScanner(reader)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "#") {
+			column := strings.Index(line, "#") + 1
+			if len(machineprogram) > 0 {
+				errs = append(errs, &SyntaxError{lineNo, column, trimmed, "header must come before machine code"})
+			} else if err := hdr.parseDirective(trimmed); err != nil {
+				errs = append(errs, &SyntaxError{lineNo, column, trimmed, err.Error()})
+			}
+			continue
+		}
+
+		words := 0
+		for column := 0; column < len(line); {
+			if unicode.IsSpace(rune(line[column])) {
+				column++
+				continue
+			}
+			start := column
+			for column < len(line) && !unicode.IsSpace(rune(line[column])) {
+				column++
+			}
+			word := line[start:column]
+			words++
+
+			if !isWord(word) {
+				msg := fmt.Sprintf("'%s' is not a 4-digit machine code word", word)
+				errs = append(errs, &SyntaxError{lineNo, utf8.RuneCountInString(line[:start]) + 1, word, msg})
+				continue
+			}
+			if strict && words > 1 {
+				msg := fmt.Sprintf("'%s' should be on a line of its own", word)
+				errs = append(errs, &SyntaxError{lineNo, utf8.RuneCountInString(line[:start]) + 1, word, msg})
+				continue
+			}
+
+			machinecode, _ := strconv.Atoi(word)
+			machineprogram = append(machineprogram, uint(machinecode))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("unable to disassemble file: %w", err)
+	}
+
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+	if hdr.hasChecksum && prog.Checksum(machineprogram) != hdr.checksum {
+		return nil, nil, fmt.Errorf("checksum %08d does not match machine code, which has checksum %08d", hdr.checksum, prog.Checksum(machineprogram))
+	}
+	return machineprogram, hdr, nil
+}
@@ -0,0 +1,63 @@
+package disasm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/ordovician/calcutron/asm"
+	"github.com/ordovician/calcutron/prog"
+)
+
+// WriteSource writes program as assembly code which assembles back into exactly
+// the same machine code words. Labels are placed on their own line and branches
+// refer to them by name. A pseudo instruction which would not survive the round
+// trip is written as its base instruction, or failing that as a DAT directive.
+// Colors are never used.
+func WriteSource(writer io.Writer, program *prog.Program) error {
+	defer func(noColor bool) {
+		color.NoColor = noColor
+	}(color.NoColor)
+	color.NoColor = true
+
+	// only labels written out are known when the source is assembled again
+	addrToLabel := canonicalLabels(program.Labels, uint(len(program.Instructions)))
+	emitted := make(prog.SymbolTable)
+	emitted.AddIOLabels()
+	for addr, label := range addrToLabel {
+		emitted[label] = addr
+	}
+
+	for addr, inst := range program.Instructions {
+		if label, ok := addrToLabel[uint(addr)]; ok {
+			if _, err := fmt.Fprintf(writer, "%s:\n", label); err != nil {
+				return err
+			}
+		}
+
+		// a pseudo instruction may have several encodings, in which case we
+		// must use the base instruction to get back the same machine code
+		word := inst.MachineCode()
+		base := DisassembleInstruction(word)
+		base.SetLabel(inst.Label())
+
+		var code string
+		for _, candidate := range []prog.Instruction{inst, base, DisassembleData(word)} {
+			code = strings.TrimSpace(candidate.SourceCode())
+			if reassembles(emitted, code, uint(addr), word) {
+				break
+			}
+		}
+
+		if _, err := fmt.Fprintf(writer, "    %s\n", code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func reassembles(labels prog.SymbolTable, code string, addr uint, word uint) bool {
+	inst, err := asm.AssembleLine(labels, code, addr)
+	return err == nil && inst != nil && inst.MachineCode() == word
+}
@@ -0,0 +1,209 @@
+// Package format rewrites Calcutron-33 assembly source code into a canonical layout.
+//
+// Labels are placed on their own line in the first column, with the instructions
+// they refer to indented underneath. Mnemonics are upper case, registers lower case
+// and operands separated by a comma and a single space. Trailing comments within a
+// group of lines not separated by a blank line are aligned into the same column.
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ordovician/calcutron/asm"
+	"github.com/ordovician/calcutron/prog"
+	"golang.org/x/exp/slices"
+)
+
+// Indentation used for instructions and comments beneath labels
+const indent = "    "
+
+// Minimum number of spaces between code and a trailing comment
+const commentGap = 2
+
+// A line of source code broken into parts that will be laid out in columns
+type line struct {
+	label   string // without trailing colon
+	code    string // canonical instruction text, without indentation
+	comment string // including the leading //
+	indent  bool   // used by comment only lines which were indented
+}
+
+func (ln *line) blank() bool {
+	return ln.label == "" && ln.code == "" && ln.comment == ""
+}
+
+// text of line before any trailing comment
+func (ln *line) prefix() string {
+	if ln.label != "" {
+		return ln.label + ":"
+	}
+	if ln.code != "" {
+		return indent + ln.code
+	}
+	if ln.indent {
+		return indent
+	}
+	return ""
+}
+
+// Turn mnemonic and operands into canonical instruction text
+func formatCode(code string) string {
+	if code == "" {
+		return ""
+	}
+
+	mnemonic, operStr := code, ""
+	if i := strings.IndexFunc(code, unicode.IsSpace); i >= 0 {
+		mnemonic, operStr = code[:i], strings.TrimSpace(code[i:])
+	}
+
+	if opcode, ok := prog.ParseOpcode(mnemonic); ok {
+		mnemonic = opcode.String()
+	}
+	if operStr == "" {
+		return mnemonic
+	}
+
+	// string literals are kept verbatim
+	if strings.HasPrefix(operStr, "\"") {
+		return fmt.Sprintf("%-5s%s", mnemonic, operStr)
+	}
+
+	operands := strings.Split(operStr, ",")
+	for i, oper := range operands {
+		operands[i] = formatOperand(strings.TrimSpace(oper))
+	}
+	return fmt.Sprintf("%-5s%s", mnemonic, strings.Join(operands, ", "))
+}
+
+// Registers are written in lower case and numbers without redundant signs
+// and leading zeros. Labels are left alone
+func formatOperand(operand string) string {
+	if len(operand) >= 2 && (operand[0] == 'x' || operand[0] == 'X') {
+		if i, err := strconv.Atoi(operand[1:]); err == nil && i >= 0 && i <= 9 {
+			return fmt.Sprintf("x%d", i)
+		}
+	}
+	if n, err := strconv.Atoi(operand); err == nil {
+		return strconv.Itoa(n)
+	}
+	return operand
+}
+
+// Break source code into lines where labels have been moved to their own lines.
+// Consecutive blank lines are collapsed into one and leading and trailing blank
+// lines are removed
+func splitSource(src []byte) []line {
+	lines := make([]line, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	for scanner.Scan() {
+		text := scanner.Text()
+		label, code, comment := prog.SplitLine(text)
+
+		if label == "" && code == "" && comment == "" {
+			if n := len(lines); n > 0 && !lines[n-1].blank() {
+				lines = append(lines, line{})
+			}
+			continue
+		}
+
+		if label != "" {
+			ln := line{label: label}
+			if code == "" {
+				ln.comment = comment
+			}
+			lines = append(lines, ln)
+		}
+
+		if code != "" {
+			lines = append(lines, line{code: formatCode(code), comment: comment})
+		} else if label == "" {
+			trimmed := strings.TrimLeft(text, " \t")
+			lines = append(lines, line{comment: comment, indent: len(trimmed) < len(text)})
+		}
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1].blank() {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Write lines with trailing comments aligned within each group of lines
+// separated by blank lines
+func layout(lines []line) []byte {
+	var buffer bytes.Buffer
+
+	for start := 0; start < len(lines); {
+		end := start
+		for end < len(lines) && !lines[end].blank() {
+			end++
+		}
+
+		// determine comment column of group
+		column := 0
+		for _, ln := range lines[start:end] {
+			if ln.comment != "" && (ln.code != "" || ln.label != "") {
+				if n := len(ln.prefix()) + commentGap; n > column {
+					column = n
+				}
+			}
+		}
+
+		for _, ln := range lines[start:end] {
+			prefix := ln.prefix()
+			switch {
+			case ln.comment == "":
+				buffer.WriteString(prefix)
+			case ln.code == "" && ln.label == "":
+				buffer.WriteString(prefix + ln.comment)
+			default:
+				fmt.Fprintf(&buffer, "%-*s%s", column, prefix, ln.comment)
+			}
+			buffer.WriteByte('\n')
+		}
+
+		// keep the single blank line separating groups
+		if end < len(lines) {
+			buffer.WriteByte('\n')
+			end++
+		}
+		start = end
+	}
+	return buffer.Bytes()
+}
+
+// Source formats assembly code src into canonical layout. The formatted code is
+// assembled and compared with the machine code of src, and an error is returned
+// if they differ or if src cannot be assembled.
+func Source(src []byte) ([]byte, error) {
+	original, err := asm.Assemble(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("cannot format code which does not assemble: %w", err)
+	}
+
+	formatted := layout(splitSource(src))
+
+	program, err := asm.Assemble(bytes.NewReader(formatted))
+	if err != nil {
+		return nil, fmt.Errorf("formatted code does not assemble. Bug in formatter: %w", err)
+	}
+
+	if !slices.Equal(machineCode(original), machineCode(program)) {
+		return nil, fmt.Errorf("formatting would change the assembled machine code")
+	}
+	return formatted, nil
+}
+
+func machineCode(program *prog.Program) []uint {
+	words := make([]uint, len(program.Instructions))
+	for i, inst := range program.Instructions {
+		words[i] = inst.MachineCode()
+	}
+	return words
+}
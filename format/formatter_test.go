@@ -0,0 +1,55 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func ExampleSource() {
+	src := `
+loop:   inp X1
+	INP x2   // second number
+  add x3,x1 ,  x2 // sum
+    OUT  x3
+
+
+    jmp loop
+`
+	formatted, err := Source([]byte(src))
+	if err != nil {
+		fmt.Println(err)
+	}
+	fmt.Print(string(formatted))
+
+	// Output:
+	// loop:
+	//     INP  x1
+	//     INP  x2          // second number
+	//     ADD  x3, x1, x2  // sum
+	//     OUT  x3
+	//
+	//     JMP  loop
+}
+
+func TestFormatExamples(t *testing.T) {
+	files, _ := filepath.Glob("../examples/*.ct33")
+	for _, file := range files {
+		src, _ := os.ReadFile(file)
+		formatted, err := Source(src)
+		if err != nil {
+			t.Errorf("unable to format %s because %v", file, err)
+			continue
+		}
+
+		// formatting should be idempotent
+		again, err := Source(formatted)
+		if err != nil {
+			t.Errorf("unable to format formatted %s because %v", file, err)
+		} else if !bytes.Equal(formatted, again) {
+			t.Errorf("formatting %s twice gives different result", file)
+		}
+	}
+}
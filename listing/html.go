@@ -0,0 +1,104 @@
+package listing
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// Colours mirror those used when printing to a terminal, see prog/colorization.go
+const stylesheet = `body { font-family: sans-serif; margin: 2em; }
+table.listing { border-collapse: collapse; font-family: monospace; font-size: 1.05em; }
+table.listing th { text-align: left; border-bottom: 1px solid #999; padding: 0.2em 0.8em; }
+table.listing td { padding: 0.1em 0.8em; white-space: pre; vertical-align: top; }
+table.listing tr:target { background: #ffc; }
+.address { color: #b58900; }
+.machinecode { color: #777; }
+.field { display: inline-block; margin-right: 0.6em; }
+.field .name { color: #999; font-size: 0.8em; margin-right: 0.2em; }
+.mnemonic { color: #0aa; font-weight: bold; }
+.number { color: #e33; }
+.register { color: #333; }
+.label { color: #2a2; font-weight: bold; text-decoration: none; }
+a.label:hover { text-decoration: underline; }
+.comment { color: #888; font-style: italic; }
+`
+
+var tokenClasses = map[tokenKind]string{
+	mnemonicToken: "mnemonic",
+	registerToken: "register",
+	numberToken:   "number",
+	labelToken:    "label",
+}
+
+func (lst *listing) writeHTML(writer io.Writer) {
+	title := html.EscapeString(lst.title)
+	fmt.Fprintln(writer, "<!DOCTYPE html>")
+	fmt.Fprintln(writer, "<html>")
+	fmt.Fprintln(writer, "<head>")
+	fmt.Fprintln(writer, `<meta charset="utf-8">`)
+	fmt.Fprintf(writer, "<title>%s</title>\n", title)
+	fmt.Fprintf(writer, "<style>\n%s</style>\n", stylesheet)
+	fmt.Fprintln(writer, "</head>")
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintf(writer, "<h1>%s</h1>\n", title)
+	fmt.Fprintln(writer, `<table class="listing">`)
+	fmt.Fprintln(writer, "<tr><th>Addr</th><th>Code</th><th>Fields</th><th>Source</th><th>Comment</th></tr>")
+
+	for _, ln := range lst.lines {
+		if ln.label == "" && len(ln.code) == 0 && ln.comment == "" {
+			continue
+		}
+
+		if ln.label != "" {
+			fmt.Fprintf(writer, `<tr id="%s">`, html.EscapeString(anchor(ln.label)))
+		} else {
+			fmt.Fprint(writer, "<tr>")
+		}
+
+		if ln.inst != nil {
+			fmt.Fprintf(writer, `<td class="address">%02d</td>`, ln.address)
+			fmt.Fprintf(writer, `<td class="machinecode">%04d</td>`, ln.inst.MachineCode())
+			fmt.Fprint(writer, "<td>")
+			for _, f := range fields(ln.inst) {
+				fmt.Fprintf(writer, `<span class="field"><span class="name">%s</span>%s</span>`, f.name, html.EscapeString(f.value))
+			}
+			fmt.Fprint(writer, "</td>")
+		} else {
+			fmt.Fprint(writer, "<td></td><td></td><td></td>")
+		}
+
+		fmt.Fprint(writer, "<td>")
+		if ln.label != "" {
+			fmt.Fprintf(writer, `<span class="label">%s:</span>`, html.EscapeString(ln.label))
+			if len(ln.code) > 0 {
+				fmt.Fprint(writer, " ")
+			}
+		}
+		lst.writeHTMLCode(writer, ln)
+		fmt.Fprint(writer, "</td>")
+
+		fmt.Fprintf(writer, `<td class="comment">%s</td>`, html.EscapeString(ln.comment))
+		fmt.Fprintln(writer, "</tr>")
+	}
+
+	fmt.Fprintln(writer, "</table>")
+	fmt.Fprintln(writer, "</body>")
+	fmt.Fprintln(writer, "</html>")
+}
+
+func (lst *listing) writeHTMLCode(writer io.Writer, ln line) {
+	for _, tok := range ln.code {
+		text := html.EscapeString(tok.text)
+		class, ok := tokenClasses[tok.kind]
+		switch {
+		case tok.kind == labelToken && lst.defined[tok.text]:
+			fmt.Fprintf(writer, `<a class="label" href="#%s">%s</a>`, html.EscapeString(anchor(tok.text)), text)
+		case ok:
+			fmt.Fprintf(writer, `<span class="%s">%s</span>`, class, text)
+		default:
+			fmt.Fprint(writer, strings.ReplaceAll(text, "\t", " "))
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package listing renders an assembled Calcutron-33 program as an annotated
+// listing in HTML or Markdown, suitable for handouts and web pages.
+//
+// Every source line is shown with its address, machine code, a breakdown of the
+// machine code digits into opcode, register and constant fields, colourised
+// source code and its comment. Labels used as operands link to the line where
+// they are defined.
+package listing
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ordovician/calcutron/asm"
+	"github.com/ordovician/calcutron/prog"
+)
+
+// Format of rendered listing
+type Format int
+
+const (
+	HTML Format = iota
+	Markdown
+)
+
+// ParseFormat turns a format name given on the command line into a Format
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "html", "htm":
+		return HTML, nil
+	case "md", "markdown":
+		return Markdown, nil
+	}
+	return HTML, fmt.Errorf("unknown listing format '%s'. Use html or md", name)
+}
+
+// Kinds of words found in source code, each rendered in its own colour
+type tokenKind int
+
+const (
+	plainToken tokenKind = iota
+	mnemonicToken
+	registerToken
+	numberToken
+	labelToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// A named group of digits in a machine code word
+type field struct {
+	name  string
+	value string
+}
+
+// A line of source code together with what it was assembled into
+type line struct {
+	label   string
+	code    []token
+	comment string
+	address uint
+	inst    prog.Instruction // nil for lines without an instruction
+}
+
+// A program ready to be rendered
+type listing struct {
+	title   string
+	lines   []line
+	defined map[string]bool // labels defined in source, which can be linked to
+}
+
+func isWordChar(ch byte) bool {
+	return ch == '_' || ch == '.' || ch == '-' ||
+		('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9')
+}
+
+func isRegister(word string) bool {
+	return len(word) == 2 && (word[0] == 'x' || word[0] == 'X') && '0' <= word[1] && word[1] <= '9'
+}
+
+// Split code of a line into mnemonic, operands and the punctuation between them
+func tokenize(code string, labels prog.SymbolTable) []token {
+	tokens := make([]token, 0)
+	first := true
+	for i := 0; i < len(code); {
+		start := i
+		switch {
+		case code[i] == '"':
+			if j := strings.IndexRune(code[i+1:], '"'); j >= 0 {
+				i += j + 2
+			} else {
+				i = len(code)
+			}
+			tokens = append(tokens, token{numberToken, code[start:i]})
+		case isWordChar(code[i]):
+			for i < len(code) && isWordChar(code[i]) {
+				i++
+			}
+			word := code[start:i]
+			kind := plainToken
+			if _, err := strconv.Atoi(word); err == nil {
+				kind = numberToken
+			} else if first {
+				kind = mnemonicToken
+			} else if isRegister(word) {
+				kind = registerToken
+			} else if _, ok := labels[word]; ok {
+				kind = labelToken
+			}
+			first = false
+			tokens = append(tokens, token{kind, word})
+		default:
+			for i < len(code) && code[i] != '"' && !isWordChar(code[i]) {
+				i++
+			}
+			tokens = append(tokens, token{plainToken, code[start:i]})
+		}
+	}
+	return tokens
+}
+
+// Break machine code of inst into its fields according to its encoding
+func fields(inst prog.Instruction) []field {
+	digits := fmt.Sprintf("%04d", inst.MachineCode())
+	opcode := inst.Opcode()
+
+	// show signed value of constant when it differs from its digits
+	constant := func(k string) string {
+		if n, _ := strconv.Atoi(k); n != inst.Constant() {
+			return fmt.Sprintf("%s (%d)", k, inst.Constant())
+		}
+		return k
+	}
+
+	op := field{"op", fmt.Sprintf("%c %v", digits[0], opcode)}
+	switch inst.PseudoCode().Encoding() {
+	case prog.RegEncoding:
+		return []field{op, {"Rd", digits[1:2]}, {"Ra", digits[2:3]}, {"Rb", digits[3:4]}}
+	case prog.ShortImmEncoding:
+		return []field{op, {"Rd", digits[1:2]}, {"Ra", digits[2:3]}, {"k", constant(digits[3:4])}}
+	case prog.LongImmEncoding:
+		return []field{op, {"Rd", digits[1:2]}, {"k", constant(digits[2:4])}}
+	default:
+		return []field{{"data", digits}}
+	}
+}
+
+// Assemble source code and pair every line with its instruction
+func build(title string, src []byte) (*listing, error) {
+	program, err := asm.Assemble(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+
+	lst := &listing{title: title, defined: make(map[string]bool)}
+	var addr uint
+	for _, text := range strings.Split(strings.TrimRight(string(src), "\n"), "\n") {
+		label, code, comment := prog.SplitLine(strings.TrimRight(text, "\r"))
+		ln := line{
+			label:   label,
+			code:    tokenize(code, program.Labels),
+			comment: comment,
+			address: addr,
+		}
+		if label != "" {
+			lst.defined[label] = true
+		}
+		if code != "" {
+			ln.inst = program.Instructions[addr]
+			addr++
+		}
+		lst.lines = append(lst.lines, ln)
+	}
+	return lst, nil
+}
+
+// Write assembles src and writes a listing of it in the given format to writer.
+// Title is used as the heading of the document, typically the name of the source file.
+func Write(writer io.Writer, title string, src []byte, format Format) error {
+	lst, err := build(title, src)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case Markdown:
+		lst.writeMarkdown(writer)
+	default:
+		lst.writeHTML(writer)
+	}
+	return nil
+}
+
+// Identifier of element a label links to
+func anchor(label string) string {
+	return "label-" + label
+}
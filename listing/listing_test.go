@@ -0,0 +1,57 @@
+package listing
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func ExampleWrite() {
+	src := `
+loop:
+    INP  x1
+    ADDI x1, -1  // decrement
+    OUT  x1
+    BRA  loop
+`
+	Write(os.Stdout, "countdown", []byte(src), Markdown)
+
+	// Output:
+	// # countdown
+	//
+	// | Addr | Code | Fields | Source | Comment |
+	// |------|------|--------|--------|---------|
+	// | | | | <a id="label-loop"></a>**loop:** |  |
+	// | 00 | `5109` | op `5 LOAD` Rd `1` Ra `0` k `9 (-1)` | **INP** `x1` |  |
+	// | 01 | `2199` | op `2 ADDI` Rd `1` k `99 (-1)` | **ADDI** `x1`, `-1` | // decrement |
+	// | 02 | `7109` | op `7 STOR` Rd `1` Ra `0` k `9 (-1)` | **OUT** `x1` |  |
+	// | 03 | `0007` | op `0 BEQ` Rd `0` Ra `0` k `7 (-3)` | **BRA** [loop](#label-loop) |  |
+}
+
+// Every label link in a listing should lead to an element with that id
+func TestHTMLLinks(t *testing.T) {
+	hrefs := regexp.MustCompile(`href="#([^"]+)"`)
+	ids := regexp.MustCompile(`id="([^"]+)"`)
+
+	files, _ := filepath.Glob("../examples/*.ct33")
+	for _, file := range files {
+		src, _ := os.ReadFile(file)
+		var buffer bytes.Buffer
+		if err := Write(&buffer, file, src, HTML); err != nil {
+			t.Errorf("unable to make listing of %s because %v", file, err)
+			continue
+		}
+
+		defined := make(map[string]bool)
+		for _, match := range ids.FindAllStringSubmatch(buffer.String(), -1) {
+			defined[match[1]] = true
+		}
+		for _, match := range hrefs.FindAllStringSubmatch(buffer.String(), -1) {
+			if !defined[match[1]] {
+				t.Errorf("%s links to undefined %s", file, match[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package listing
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var spaces = regexp.MustCompile(`\s+`)
+
+// Escape characters which have special meaning in Markdown table cells
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", "&lt;",
+)
+
+// Markdown has no colours, so mnemonics are written in bold, labels as links
+// and registers and numbers as code
+func (lst *listing) writeMarkdown(writer io.Writer) {
+	fmt.Fprintf(writer, "# %s\n\n", markdownEscaper.Replace(lst.title))
+	fmt.Fprintln(writer, "| Addr | Code | Fields | Source | Comment |")
+	fmt.Fprintln(writer, "|------|------|--------|--------|---------|")
+
+	for _, ln := range lst.lines {
+		if ln.label == "" && len(ln.code) == 0 && ln.comment == "" {
+			continue
+		}
+
+		if ln.inst != nil {
+			parts := make([]string, 0, 4)
+			for _, f := range fields(ln.inst) {
+				parts = append(parts, fmt.Sprintf("%s `%s`", f.name, f.value))
+			}
+			fmt.Fprintf(writer, "| %02d | `%04d` | %s ", ln.address, ln.inst.MachineCode(), strings.Join(parts, " "))
+		} else {
+			fmt.Fprint(writer, "| | | ")
+		}
+
+		fmt.Fprint(writer, "| ")
+		if ln.label != "" {
+			fmt.Fprintf(writer, `<a id="%s"></a>**%s:**`, anchor(ln.label), markdownEscaper.Replace(ln.label))
+			if len(ln.code) > 0 {
+				fmt.Fprint(writer, " ")
+			}
+		}
+		lst.writeMarkdownCode(writer, ln)
+
+		fmt.Fprintf(writer, " | %s |\n", markdownEscaper.Replace(ln.comment))
+	}
+}
+
+func (lst *listing) writeMarkdownCode(writer io.Writer, ln line) {
+	for _, tok := range ln.code {
+		switch tok.kind {
+		case mnemonicToken:
+			fmt.Fprintf(writer, "**%s**", tok.text)
+		case registerToken, numberToken:
+			fmt.Fprintf(writer, "`%s`", strings.ReplaceAll(tok.text, "|", `\|`))
+		case labelToken:
+			if lst.defined[tok.text] {
+				fmt.Fprintf(writer, "[%s](#%s)", markdownEscaper.Replace(tok.text), anchor(tok.text))
+			} else {
+				fmt.Fprint(writer, markdownEscaper.Replace(tok.text))
+			}
+		default:
+			// alignment is lost in a Markdown table anyway
+			fmt.Fprint(writer, markdownEscaper.Replace(spaces.ReplaceAllString(tok.text, " ")))
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package lsp
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/ordovician/calcutron/asm"
+	"github.com/ordovician/calcutron/prog"
+)
+
+// A word in the source code with its location
+type token struct {
+	text string
+	rng  Range
+}
+
+// Information about a single source code line
+type sourceLine struct {
+	text     string
+	label    *token  // label defined on line
+	mnemonic *token  // mnemonic of instruction, if any
+	operands []token // operands following the mnemonic
+	address  uint    // address of instruction on line
+	inst     prog.Instruction
+	err      error
+}
+
+// An open assembly code document and what we know about it
+type document struct {
+	uri    string
+	lines  []sourceLine
+	labels prog.SymbolTable
+}
+
+func isWordChar(ch byte) bool {
+	return ch == '_' || ch == '.' || ch == '-' ||
+		('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9')
+}
+
+// Split the code part of a line, before any comment, into words with their
+// positions. Comments are found the same way as by the assembler, so that a
+// "//" inside a string literal is not taken for a comment
+func tokenize(lineNo int, text string) []token {
+	if _, _, comment := prog.SplitLine(text); comment != "" {
+		text = strings.TrimRightFunc(text, unicode.IsSpace)
+		text = text[:len(text)-len(comment)]
+	}
+
+	tokens := make([]token, 0)
+	for i := 0; i < len(text); {
+		if !isWordChar(text[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(text) && isWordChar(text[i]) {
+			i++
+		}
+		tokens = append(tokens, token{
+			text: text[start:i],
+			rng: Range{
+				Start: Position{lineNo, start},
+				End:   Position{lineNo, i},
+			},
+		})
+	}
+	return tokens
+}
+
+// Analyze source code text by assembling each line and locating labels
+func newDocument(uri, text string) *document {
+	doc := &document{uri: uri}
+	doc.labels = prog.ReadSymTable(strings.NewReader(text))
+	doc.labels.AddIOLabels()
+
+	var addr uint
+	for lineNo, text := range strings.Split(text, "\n") {
+		text = strings.TrimRight(text, "\r")
+		line := sourceLine{text: text, address: addr}
+
+		tokens := tokenize(lineNo, text)
+		label, code, _ := prog.SplitLine(text)
+		if label != "" && len(tokens) > 0 {
+			line.label = &tokens[0]
+			tokens = tokens[1:]
+		}
+		if code != "" && len(tokens) > 0 {
+			line.mnemonic = &tokens[0]
+			line.operands = tokens[1:]
+			line.inst, line.err = asm.AssembleLine(doc.labels, text, addr)
+			addr++
+		}
+		doc.lines = append(doc.lines, line)
+	}
+	return doc
+}
+
+// Location of label definition
+func (doc *document) definition(label string) (Range, bool) {
+	for _, line := range doc.lines {
+		if line.label != nil && line.label.text == label {
+			return line.label.rng, true
+		}
+	}
+	return Range{}, false
+}
+
+// All places where label is used as an operand
+func (doc *document) references(label string) []Range {
+	refs := make([]Range, 0)
+	for _, line := range doc.lines {
+		for _, operand := range line.operands {
+			if operand.text == label {
+				refs = append(refs, operand.rng)
+			}
+		}
+	}
+	return refs
+}
+
+// Find the line and token at given position. Returns nil token if there is
+// no word under the cursor
+func (doc *document) tokenAt(pos Position) (*sourceLine, *token) {
+	if pos.Line < 0 || pos.Line >= len(doc.lines) {
+		return nil, nil
+	}
+	line := &doc.lines[pos.Line]
+
+	if line.label != nil && line.label.rng.Contains(pos) {
+		return line, line.label
+	}
+	if line.mnemonic != nil && line.mnemonic.rng.Contains(pos) {
+		return line, line.mnemonic
+	}
+	for i := range line.operands {
+		if line.operands[i].rng.Contains(pos) {
+			return line, &line.operands[i]
+		}
+	}
+	return line, nil
+}
+
+// Name of label under cursor, whether at its definition or where it is used
+func (doc *document) labelAt(pos Position) (string, bool) {
+	line, tok := doc.tokenAt(pos)
+	if tok == nil || tok == line.mnemonic {
+		return "", false
+	}
+	if _, ok := doc.labels[tok.text]; !ok {
+		return "", false
+	}
+	return tok.text, true
+}
+
+// Diagnostics for every line which fails to assemble
+func (doc *document) diagnostics() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+	for lineNo, line := range doc.lines {
+		if line.err == nil {
+			continue
+		}
+
+		start := 0
+		if line.mnemonic != nil {
+			start = line.mnemonic.rng.Start.Character
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range: Range{
+				Start: Position{lineNo, start},
+				End:   Position{lineNo, len(strings.TrimRight(line.text, " \t"))},
+			},
+			Severity: SeverityError,
+			Source:   "cutron",
+			Message:  line.err.Error(),
+		})
+	}
+	return diagnostics
+}
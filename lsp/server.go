@@ -0,0 +1,391 @@
+// Package lsp implements a Language Server Protocol server for Calcutron-33
+// assembly code, so that any editor with LSP support gets diagnostics, hover
+// information, label navigation, renaming and completion of mnemonics.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/ordovician/calcutron/asm"
+	"github.com/ordovician/calcutron/prog"
+)
+
+// Server keeps track of documents opened by the editor client
+type Server struct {
+	documents map[string]*document
+	writer    io.Writer
+	mutex     sync.Mutex // guards writer
+	shutdown  bool
+}
+
+func NewServer() *Server {
+	return &Server{
+		documents: make(map[string]*document),
+	}
+}
+
+// Serve reads requests from reader and writes responses to writer until the
+// client sends an exit notification or reader is closed
+func (server *Server) Serve(reader io.Reader, writer io.Writer) error {
+	// all text sent to editor should be free of ANSI escape codes
+	color.NoColor = true
+	server.writer = writer
+
+	bufReader := bufio.NewReader(reader)
+	for {
+		body, err := readMessage(bufReader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read message from client: %w", err)
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			server.send(errorResponse{"2.0", nil, &responseError{parseError, err.Error()}})
+			continue
+		}
+
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, err := server.handle(req.Method, req.Params)
+		if req.ID == nil {
+			// notifications don't get any response
+			continue
+		}
+
+		var respErr *responseError
+		if errors.As(err, &respErr) {
+			server.send(errorResponse{"2.0", req.ID, respErr})
+		} else if err != nil {
+			server.send(errorResponse{"2.0", req.ID, &responseError{requestFailed, err.Error()}})
+		} else {
+			server.send(response{"2.0", req.ID, result})
+		}
+	}
+}
+
+func (server *Server) send(value any) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	writeMessage(server.writer, value)
+}
+
+func (server *Server) notify(method string, params any) {
+	server.send(notification{"2.0", method, params})
+}
+
+// Decode params and call fn with them
+func call[T any](params json.RawMessage, fn func(T) (any, error)) (any, error) {
+	var value T
+	if err := json.Unmarshal(params, &value); err != nil {
+		return nil, &responseError{invalidParams, err.Error()}
+	}
+	return fn(value)
+}
+
+func (server *Server) handle(method string, params json.RawMessage) (result any, err error) {
+	// a bug triggered by one message should not take down the whole server
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("internal error handling %s: %v", method, r)
+		}
+	}()
+
+	switch method {
+	case "initialize":
+		return server.initialize(), nil
+	case "initialized", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "shutdown":
+		server.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		return call(params, server.didOpen)
+	case "textDocument/didChange":
+		return call(params, server.didChange)
+	case "textDocument/didClose":
+		return call(params, server.didClose)
+	case "textDocument/hover":
+		return call(params, server.hover)
+	case "textDocument/definition":
+		return call(params, server.definition)
+	case "textDocument/references":
+		return call(params, server.references)
+	case "textDocument/rename":
+		return call(params, server.rename)
+	case "textDocument/completion":
+		return call(params, server.completion)
+	case "textDocument/codeAction":
+		return call(params, server.codeAction)
+	}
+	return nil, &responseError{methodNotFound, fmt.Sprintf("method '%s' is not supported", method)}
+}
+
+func (server *Server) initialize() *InitializeResult {
+	var result InitializeResult
+	result.ServerInfo.Name = "cutron"
+	result.Capabilities = ServerCapabilities{
+		TextDocumentSync:   1, // always send full text of document
+		HoverProvider:      true,
+		DefinitionProvider: true,
+		ReferencesProvider: true,
+		RenameProvider:     true,
+		CodeActionProvider: true,
+		CompletionProvider: struct{}{},
+	}
+	return &result
+}
+
+// Analyze document text and send diagnostics to client
+func (server *Server) update(uri, text string) {
+	doc := newDocument(uri, text)
+	server.documents[uri] = doc
+	server.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: doc.diagnostics(),
+	})
+}
+
+func (server *Server) didOpen(params DidOpenTextDocumentParams) (any, error) {
+	server.update(params.TextDocument.URI, params.TextDocument.Text)
+	return nil, nil
+}
+
+func (server *Server) didChange(params DidChangeTextDocumentParams) (any, error) {
+	n := len(params.ContentChanges)
+	if n > 0 {
+		server.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+	}
+	return nil, nil
+}
+
+func (server *Server) didClose(params DidCloseTextDocumentParams) (any, error) {
+	delete(server.documents, params.TextDocument.URI)
+	return nil, nil
+}
+
+func (server *Server) lookup(id TextDocumentIdentifier) (*document, error) {
+	doc, ok := server.documents[id.URI]
+	if !ok {
+		return nil, fmt.Errorf("document %s has not been opened", id.URI)
+	}
+	return doc, nil
+}
+
+func (server *Server) hover(params TextDocumentPositionParams) (any, error) {
+	doc, err := server.lookup(params.TextDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	line, tok := doc.tokenAt(params.Position)
+	if tok == nil {
+		return nil, nil
+	}
+
+	var text string
+	if label, ok := doc.labelAt(params.Position); ok {
+		text = fmt.Sprintf("label `%s` at address %02d", label, doc.labels[label])
+	} else if line.inst != nil && line.err == nil {
+		text = describeInstruction(line.inst, line.address)
+	} else {
+		return nil, nil
+	}
+
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: text},
+		Range:    &tok.rng,
+	}, nil
+}
+
+// Markdown description of an assembled instruction showing its encoding
+func describeInstruction(inst prog.Instruction, address uint) string {
+	var builder strings.Builder
+	code := inst.MachineCode()
+	opcode := inst.PseudoCode()
+
+	fmt.Fprintf(&builder, "```\n%s\n```\n", strings.TrimSpace(inst.SourceCode()))
+	fmt.Fprintf(&builder, "Machine code `%04d` at address %02d\n\n", code, address)
+
+	digits := fmt.Sprintf("%04d", code)
+	switch opcode.Encoding() {
+	case prog.RegEncoding:
+		fmt.Fprintf(&builder, "opcode %c (%v), Rd x%c, Ra x%c, Rb x%c", digits[0], inst.Opcode(), digits[1], digits[2], digits[3])
+	case prog.ShortImmEncoding:
+		fmt.Fprintf(&builder, "opcode %c (%v), Rd x%c, Ra x%c, k %d", digits[0], inst.Opcode(), digits[1], digits[2], inst.Constant())
+	case prog.LongImmEncoding:
+		fmt.Fprintf(&builder, "opcode %c (%v), Rd x%c, k %d", digits[0], inst.Opcode(), digits[1], inst.Constant())
+	case prog.DataEncoding:
+		fmt.Fprintf(&builder, "data word %s", digits)
+	}
+
+	if min, max := opcode.ConstantRange(); min != max {
+		fmt.Fprintf(&builder, "\n\nconstant k must be in range %d to %d", min, max)
+	}
+	return builder.String()
+}
+
+func (server *Server) definition(params TextDocumentPositionParams) (any, error) {
+	doc, err := server.lookup(params.TextDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	label, ok := doc.labelAt(params.Position)
+	if !ok {
+		return nil, nil
+	}
+	rng, ok := doc.definition(label)
+	if !ok {
+		return nil, nil
+	}
+	return []Location{{URI: doc.uri, Range: rng}}, nil
+}
+
+func (server *Server) references(params ReferenceParams) (any, error) {
+	doc, err := server.lookup(params.TextDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	label, ok := doc.labelAt(params.Position)
+	if !ok {
+		return nil, nil
+	}
+
+	locations := make([]Location, 0)
+	if rng, ok := doc.definition(label); ok && params.Context.IncludeDeclaration {
+		locations = append(locations, Location{URI: doc.uri, Range: rng})
+	}
+	for _, rng := range doc.references(label) {
+		locations = append(locations, Location{URI: doc.uri, Range: rng})
+	}
+	return locations, nil
+}
+
+// Check that name can be used as a label without being mistaken for something else
+func validLabel(name string) error {
+	if name == "" {
+		return errors.New("label cannot be empty")
+	}
+	// '-' is a word character only so that negative numbers are single words
+	if strings.Contains(name, "-") {
+		return fmt.Errorf("label '%s' cannot contain '-'", name)
+	}
+	for i := 0; i < len(name); i++ {
+		if !isWordChar(name[i]) {
+			return fmt.Errorf("label '%s' contains invalid character '%c'", name, name[i])
+		}
+	}
+	if '0' <= name[0] && name[0] <= '9' {
+		return fmt.Errorf("label '%s' cannot start with a digit", name)
+	}
+	if _, ok := prog.ParseOpcode(name); ok {
+		return fmt.Errorf("label '%s' is a mnemonic", name)
+	}
+	if len(name) == 2 && (name[0] == 'x' || name[0] == 'X') && '0' <= name[1] && name[1] <= '9' {
+		return fmt.Errorf("label '%s' is a register", name)
+	}
+	return nil
+}
+
+func (server *Server) rename(params RenameParams) (any, error) {
+	doc, err := server.lookup(params.TextDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	label, ok := doc.labelAt(params.Position)
+	if !ok {
+		return nil, fmt.Errorf("there is no label at cursor to rename")
+	}
+	if err := validLabel(params.NewName); err != nil {
+		return nil, err
+	}
+	if _, exists := doc.labels[params.NewName]; exists {
+		return nil, fmt.Errorf("label '%s' already exists", params.NewName)
+	}
+
+	edits := make([]TextEdit, 0)
+	if rng, ok := doc.definition(label); ok {
+		edits = append(edits, TextEdit{Range: rng, NewText: params.NewName})
+	}
+	for _, rng := range doc.references(label) {
+		edits = append(edits, TextEdit{Range: rng, NewText: params.NewName})
+	}
+
+	return &WorkspaceEdit{
+		Changes: map[string][]TextEdit{doc.uri: edits},
+	}, nil
+}
+
+func (server *Server) completion(params TextDocumentPositionParams) (any, error) {
+	doc, err := server.lookup(params.TextDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]CompletionItem, 0)
+	line, tok := doc.tokenAt(params.Position)
+	if line == nil {
+		return items, nil
+	}
+
+	// We complete a mnemonic when there is nothing on the line yet except
+	// perhaps a label, or we are at the first word after the label
+	atMnemonic := line.mnemonic == nil || tok == line.mnemonic
+	if atMnemonic {
+		for _, opstr := range prog.AllOpcodeStrings {
+			items = append(items, CompletionItem{Label: opstr, Kind: CompletionKeyword})
+		}
+		return items, nil
+	}
+
+	for label, addr := range doc.labels {
+		items = append(items, CompletionItem{
+			Label:  label,
+			Kind:   CompletionVariable,
+			Detail: fmt.Sprintf("address %02d", addr),
+		})
+	}
+	return items, nil
+}
+
+// Quick fixes for errors on lines within requested range
+func (server *Server) codeAction(params CodeActionParams) (any, error) {
+	doc, err := server.lookup(params.TextDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]CodeAction, 0)
+	for lineNo := params.Range.Start.Line; lineNo <= params.Range.End.Line && lineNo < len(doc.lines); lineNo++ {
+		line := doc.lines[lineNo]
+		for _, fix := range asm.Suggestions(line.err) {
+			edit := TextEdit{
+				Range: Range{
+					Start: Position{lineNo, 0},
+					End:   Position{lineNo, len(line.text)},
+				},
+				NewText: strings.Join(asm.ApplySuggestion(line.text, fix), "\n"),
+			}
+			actions = append(actions, CodeAction{
+				Title: fix.Message,
+				Kind:  "quickfix",
+				Edit:  &WorkspaceEdit{Changes: map[string][]TextEdit{doc.uri: {edit}}},
+			})
+		}
+	}
+	return actions, nil
+}
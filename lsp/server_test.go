@@ -0,0 +1,248 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// An in-process client talking JSON-RPC to a server over pipes
+type client struct {
+	t      *testing.T
+	writer io.WriteCloser
+	reader *bufio.Reader
+	nextID int
+}
+
+func newClient(t *testing.T) *client {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	go func() {
+		NewServer().Serve(serverReader, serverWriter)
+		serverWriter.Close()
+	}()
+
+	c := &client{t: t, writer: clientWriter, reader: bufio.NewReader(clientReader)}
+	c.call("initialize", map[string]any{}, nil)
+	return c
+}
+
+// Read next message from server
+func (c *client) read() map[string]json.RawMessage {
+	body, err := readMessage(c.reader)
+	if err != nil {
+		c.t.Fatalf("failed to read message from server: %v", err)
+	}
+	var msg map[string]json.RawMessage
+	json.Unmarshal(body, &msg)
+	return msg
+}
+
+func (c *client) notify(method string, params any) {
+	writeMessage(c.writer, map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// Send request and decode result into result. Returns error message if any
+func (c *client) call(method string, params any, result any) string {
+	c.nextID++
+	writeMessage(c.writer, map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+
+	msg := c.read()
+	if errMsg, ok := msg["error"]; ok {
+		var respErr responseError
+		json.Unmarshal(errMsg, &respErr)
+		return respErr.Message
+	}
+	if result != nil {
+		json.Unmarshal(msg["result"], result)
+	}
+	return ""
+}
+
+// Open document and return diagnostics published by server
+func (c *client) open(uri, text string) []Diagnostic {
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "ct33", Text: text},
+	})
+	msg := c.read()
+	var params PublishDiagnosticsParams
+	json.Unmarshal(msg["params"], &params)
+	return params.Diagnostics
+}
+
+func (c *client) close() {
+	c.notify("exit", nil)
+	c.writer.Close()
+}
+
+func at(uri string, line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{line, character},
+	}
+}
+
+const source = `loop:
+    INP  x1
+    LODI x2, 90
+    BEQ  x1, x0, loop
+    JMP  loop
+`
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	diagnostics := c.open("file:///adder.ct33", source)
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic got %d", len(diagnostics))
+	}
+	if diagnostics[0].Range.Start.Line != 2 {
+		t.Errorf("expected diagnostic on line 2 got %d", diagnostics[0].Range.Start.Line)
+	}
+}
+
+func TestPartialLines(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	// lines being typed are reported as errors rather than crashing the server
+	for _, line := range []string{"DAT", "ADD", "ADD x1", "SUB", "SUBI", "INC", "DEC", "LOAD", "BEQ x1"} {
+		diagnostics := c.open("file:///partial.ct33", "start:\n    "+line+"\n")
+		if len(diagnostics) != 1 || diagnostics[0].Range.Start.Line != 1 {
+			t.Errorf("expected one diagnostic on line 1 for '%s', got %v", line, diagnostics)
+		}
+	}
+
+	var hover Hover
+	if errMsg := c.call("textDocument/hover", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: "file:///partial.ct33"},
+		Position:     Position{1, 5},
+	}, &hover); errMsg != "" {
+		t.Errorf("expected server to keep answering requests, got %s", errMsg)
+	}
+}
+
+func TestRecoverFromPanic(t *testing.T) {
+	// a missing document is a bug in the server, which should fail the request
+	// rather than crash the server
+	server := NewServer()
+	server.documents["file:///broken.ct33"] = nil
+	params := json.RawMessage(`{"textDocument":{"uri":"file:///broken.ct33"},"position":{"line":0,"character":0}}`)
+	if _, err := server.handle("textDocument/hover", params); err == nil {
+		t.Errorf("expected panic while handling request to be returned as error")
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	c.open("file:///adder.ct33", source)
+
+	var hover Hover
+	c.call("textDocument/hover", at("file:///adder.ct33", 3, 5), &hover)
+	if !strings.Contains(hover.Contents.Value, "`0108`") {
+		t.Errorf("hover should show machine code 0108, got %s", hover.Contents.Value)
+	}
+}
+
+func TestDefinitionAndReferences(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	uri := "file:///adder.ct33"
+	c.open(uri, source)
+
+	var locations []Location
+	c.call("textDocument/definition", at(uri, 4, 10), &locations)
+	if len(locations) != 1 || locations[0].Range.Start != (Position{0, 0}) {
+		t.Errorf("expected definition of loop at 0:0 got %v", locations)
+	}
+
+	params := ReferenceParams{TextDocumentPositionParams: at(uri, 0, 2)}
+	params.Context.IncludeDeclaration = true
+	c.call("textDocument/references", params, &locations)
+	if len(locations) != 3 {
+		t.Errorf("expected 3 references to loop got %d", len(locations))
+	}
+}
+
+func TestRename(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	uri := "file:///adder.ct33"
+	c.open(uri, source)
+
+	var edit WorkspaceEdit
+	c.call("textDocument/rename", RenameParams{at(uri, 3, 20), "start"}, &edit)
+	if n := len(edit.Changes[uri]); n != 3 {
+		t.Errorf("expected 3 edits got %d", n)
+	}
+
+	if msg := c.call("textDocument/rename", RenameParams{at(uri, 3, 20), "x3"}, nil); msg == "" {
+		t.Errorf("renaming label to register name should fail")
+	}
+	if msg := c.call("textDocument/rename", RenameParams{at(uri, 3, 20), "my-loop"}, nil); msg == "" {
+		t.Errorf("renaming label to name with '-' should fail")
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	uri := "file:///adder.ct33"
+	c.open(uri, source+"    LO")
+
+	var items []CompletionItem
+	c.call("textDocument/completion", at(uri, 5, 6), &items)
+	found := false
+	for _, item := range items {
+		found = found || item.Label == "LODI"
+	}
+	if !found {
+		t.Errorf("expected LODI among mnemonic completions")
+	}
+
+	c.call("textDocument/completion", at(uri, 4, 10), &items)
+	if len(items) == 0 || items[0].Kind != CompletionVariable {
+		t.Errorf("expected label completions for operand")
+	}
+}
+
+func TestCodeAction(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	uri := "file:///adder.ct33"
+	c.open(uri, source)
+
+	var actions []CodeAction
+	c.call("textDocument/codeAction", CodeActionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        Range{Position{2, 0}, Position{2, 0}},
+	}, &actions)
+
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 code action got %d", len(actions))
+	}
+	edits := actions[0].Edit.Changes[uri]
+	if len(edits) != 1 || edits[0].NewText != "    LODI x2, 49\n    ADDI x2, 41" {
+		t.Errorf("unexpected edit %v", edits)
+	}
+}
+
+func TestTokenizeString(t *testing.T) {
+	// "//" inside a string literal does not start a comment
+	tokens := tokenize(0, `msg: STR "a//b" // greeting`)
+	var words []string
+	for _, tok := range tokens {
+		words = append(words, tok.text)
+	}
+	if strings.Join(words, " ") != "msg STR a b" {
+		t.Errorf("expected words msg STR a b, got %v", words)
+	}
+	if last := tokens[len(tokens)-1]; last.rng.Start.Character != 13 {
+		t.Errorf("expected b to start at 13, got %d", last.rng.Start.Character)
+	}
+}
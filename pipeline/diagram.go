@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Width of each cycle column in diagrams
+const columnWidth = 4
+
+// Write diagram showing which stage each remembered instruction was in for
+// every cycle. An instruction held up in a stage is shown with -- for every
+// cycle it had to wait
+//
+//	Cycle                  1   2   3   4   5   6   7
+//	00 LOAD x1, x0, 5      IF  ID  EX  MEM WB
+//	01 ADD  x2, x1, x1         IF  ID  --  EX  MEM WB   stalled 1 for x1 from 00
+func (pipe *Pipeline) WriteDiagram(writer io.Writer) {
+	if len(pipe.Timings) == 0 {
+		return
+	}
+	first := pipe.Timings[0].Stages[Fetch]
+	last := pipe.Timings[len(pipe.Timings)-1].Stages[WriteBack]
+
+	sourceWidth := len("Cycle")
+	for _, timing := range pipe.Timings {
+		if n := len(timing.Source) + 3; n > sourceWidth {
+			sourceWidth = n
+		}
+	}
+
+	var header strings.Builder
+	fmt.Fprintf(&header, "%-*s  ", sourceWidth, "Cycle")
+	for cycle := first; cycle <= last; cycle++ {
+		fmt.Fprintf(&header, "%-*d", columnWidth, cycle)
+	}
+	fmt.Fprintln(writer, strings.TrimRight(header.String(), " "))
+
+	for _, timing := range pipe.Timings {
+		var row strings.Builder
+		for cycle := first; cycle <= timing.Stages[WriteBack]; cycle++ {
+			fmt.Fprintf(&row, "%-*s", columnWidth, timing.cell(cycle))
+		}
+		line := fmt.Sprintf("%02d %-*s  %-*s %s", timing.PC, sourceWidth-3, timing.Source, int(last-first+1)*columnWidth, row.String(), timing.Note)
+		fmt.Fprintln(writer, strings.TrimRight(line, " "))
+	}
+}
+
+// What to show for instruction in cycle of diagram
+func (timing *Timing) cell(cycle uint) string {
+	for stage := WriteBack; stage >= Fetch; stage-- {
+		switch {
+		case cycle == timing.Stages[stage]:
+			return stage.String()
+		case cycle > timing.Stages[stage]:
+			return "--"
+		}
+	}
+	return ""
+}
+
+// Write summary of instructions executed, cycles taken and where cycles were lost
+func (pipe *Pipeline) WriteSummary(writer io.Writer) {
+	forwarding := "off"
+	if pipe.Forwarding {
+		forwarding = "on"
+	}
+	cpi := 0.0
+	if pipe.Instructions > 0 {
+		cpi = float64(pipe.Cycles) / float64(pipe.Instructions)
+	}
+
+	fmt.Fprintf(writer, "Instructions:          %d\n", pipe.Instructions)
+	fmt.Fprintf(writer, "Cycles:                %d\n", pipe.Cycles)
+	fmt.Fprintf(writer, "CPI:                   %.2f\n", cpi)
+	fmt.Fprintf(writer, "Data stall cycles:     %d\n", pipe.DataStalls)
+	fmt.Fprintf(writer, "Control stall cycles:  %d\n", pipe.ControlStalls)
+	fmt.Fprintf(writer, "Forwarding:            %s, %d values forwarded\n", forwarding, pipe.Forwards)
+	if pipe.Predictor != nil {
+		fmt.Fprintf(writer, "Branch prediction:     %s, %d mispredicted\n", pipe.Predictor.Description(), pipe.Mispredictions)
+	}
+}
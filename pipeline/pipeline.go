@@ -0,0 +1,184 @@
+// Package pipeline shows how a classic five stage RISC pipeline, as found in
+// RISC-V textbooks, would execute a Calcutron-33 program.
+//
+// Instructions pass through the stages IF (fetch), ID (decode and read
+// registers), EX (execute and resolve branches), MEM (access memory) and WB
+// (write back to registers), one stage per cycle. An instruction needing a
+// register written by an instruction still in the pipeline must stall until
+// the value is ready, which with forwarding is straight after EX, or after MEM
+// for LOAD. Without forwarding it has to wait for WB. Instructions are fetched
+// assuming branches are not taken, so a taken branch or jump flushes the
+// instructions fetched after it, unless a branch predictor is used.
+//
+// A Pipeline is a tracer which works out timing from the instructions the
+// simulator executes, so running a program pipelined always gives exactly the
+// same results as running it without.
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/ordovician/calcutron/disasm"
+	"github.com/ordovician/calcutron/predict"
+	"github.com/ordovician/calcutron/prog"
+	"github.com/ordovician/calcutron/sim"
+)
+
+// Stage of the pipeline
+type Stage int
+
+const (
+	Fetch        Stage = iota // IF reads instruction from memory
+	Decode                    // ID decodes instruction and reads registers
+	Execute                   // EX calculates result and decides whether to branch
+	MemoryAccess              // MEM loads from or stores to memory
+	WriteBack                 // WB writes result to register
+	NumStages
+)
+
+var stageNames = [NumStages]string{"IF", "ID", "EX", "MEM", "WB"}
+
+func (stage Stage) String() string {
+	return stageNames[stage]
+}
+
+// Number of instructions shown in pipeline diagrams unless told otherwise
+const DefaultDiagramSize = 20
+
+type Options struct {
+	Forwarding  bool // pass results straight from EX and MEM to instructions needing them
+	DiagramSize int  // number of instructions remembered for the pipeline diagram
+
+	// guesses which way conditional branches go, so only mispredicted branches
+	// flush the pipeline. Branches are predicted not taken when nil
+	Predictor predict.Predictor
+}
+
+// Cycles in which an executed instruction entered each stage of the pipeline,
+// counting from cycle 1
+type Timing struct {
+	Step   uint
+	PC     uint
+	Source string
+	Stages [NumStages]uint
+	Note   string // why instruction was delayed or caused a delay
+}
+
+// Instruction which last wrote a register
+type producer struct {
+	pc     uint
+	load   bool // result ready after MEM rather than EX
+	stages [NumStages]uint
+}
+
+type Pipeline struct {
+	Options
+	Instructions   uint     // number of instructions executed
+	Cycles         uint     // cycle in which the last instruction wrote back
+	DataStalls     uint     // cycles instructions waited for registers
+	ControlStalls  uint     // cycles lost to flushing instructions after taken branches
+	Forwards       uint     // register values forwarded rather than read from registers
+	Mispredictions uint     // conditional branches predicted wrongly by Predictor
+	Timings        []Timing // first DiagramSize instructions
+
+	last      Timing            // previously executed instruction
+	redirect  uint              // earliest cycle to fetch from after a taken branch
+	producers map[uint]producer // instruction giving each register its value
+}
+
+func New(options Options) *Pipeline {
+	return &Pipeline{
+		Options:   options,
+		redirect:  1,
+		producers: make(map[uint]producer),
+	}
+}
+
+func max(a, b uint) uint {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (pipe *Pipeline) Trace(record *sim.TraceRecord) {
+	inst := disasm.DisassembleInstruction(record.Word)
+	prev := pipe.last.Stages
+	timing := Timing{Step: record.Step, PC: record.PC, Source: record.Source}
+	stages := &timing.Stages
+
+	// a stage is free once the previous instruction moves on to the next stage
+	stages[Fetch] = max(prev[Decode], pipe.redirect)
+	stages[Decode] = max(stages[Fetch]+1, prev[Execute])
+	if pipe.Instructions > 0 {
+		pipe.ControlStalls += stages[Decode] - max(prev[Decode]+1, prev[Execute])
+	}
+	stages[Execute] = max(stages[Decode]+1, prev[MemoryAccess])
+
+	// wait for registers written by instructions still in the pipeline
+	seen := make(map[uint]bool)
+	for _, read := range record.Reads {
+		prod, ok := pipe.producers[read.Register]
+		if !ok || seen[read.Register] {
+			continue
+		}
+		seen[read.Register] = true
+
+		// registers are written in the first half of WB and read in the second half of ID
+		ready := prod.stages[WriteBack] + 1
+		if pipe.Forwarding && prod.load {
+			ready = prod.stages[MemoryAccess] + 1
+		} else if pipe.Forwarding {
+			ready = prod.stages[Execute] + 1
+		}
+
+		if ready > stages[Execute] {
+			stall := ready - stages[Execute]
+			pipe.DataStalls += stall
+			timing.Note = fmt.Sprintf("stalled %d for x%d from %02d", stall, read.Register, prod.pc)
+			stages[Execute] = ready
+		}
+		if prod.stages[WriteBack] >= stages[Execute] {
+			pipe.Forwards++
+		}
+	}
+
+	stages[MemoryAccess] = max(stages[Execute]+1, prev[WriteBack])
+	stages[WriteBack] = stages[MemoryAccess] + 1
+
+	for _, write := range record.Writes {
+		pipe.producers[write.Register] = producer{
+			pc:     record.PC,
+			load:   inst.Opcode() == prog.LOAD,
+			stages: timing.Stages,
+		}
+	}
+
+	// branch destination is known after EX, and a branch to itself halts
+	flush := record.NextPC != record.PC+1 && record.NextPC != record.PC
+	if target, taken, ok := predict.Branch(record); ok && pipe.Predictor != nil {
+		flush = pipe.Predictor.Predict(record.PC, target) != taken
+		pipe.Predictor.Update(record.PC, target, taken)
+		if flush {
+			pipe.Mispredictions++
+		}
+	}
+	if flush {
+		pipe.redirect = stages[Execute] + 1
+		if timing.Note != "" {
+			timing.Note += ", "
+		}
+		if pipe.Predictor != nil {
+			timing.Note += "mispredicted, flushed 2"
+		} else {
+			timing.Note += "taken, flushed 2"
+		}
+	}
+
+	pipe.Instructions++
+	pipe.Cycles = stages[WriteBack]
+	pipe.last = timing
+	if len(pipe.Timings) < pipe.DiagramSize {
+		pipe.Timings = append(pipe.Timings, timing)
+	}
+}
@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ordovician/calcutron/asm"
+	"github.com/ordovician/calcutron/predict"
+	"github.com/ordovician/calcutron/sim"
+)
+
+func runPipeline(t *testing.T, source string, forwarding bool) *Pipeline {
+	program, err := asm.Assemble(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("failed to assemble %q because %v", source, err)
+	}
+	comp, _ := sim.NewComputer(program)
+	pipe := New(Options{Forwarding: forwarding, DiagramSize: DefaultDiagramSize})
+	comp.Tracer = pipe
+	comp.Run(100)
+	return pipe
+}
+
+func TestHazards(t *testing.T) {
+	tests := []struct {
+		name          string
+		source        string
+		forwarding    bool
+		dataStalls    uint
+		controlStalls uint
+	}{
+		{"independent", "INC x1\nINC x2\nINC x3\nHLT", true, 0, 0},
+		{"forwarded from EX", "INC x1\nADD x2, x1, x1\nHLT", true, 0, 0},
+		{"waiting for WB", "INC x1\nADD x2, x1, x1\nHLT", false, 2, 0},
+		{"load use", "LOAD x1, x0, 3\nADD x2, x1, x1\nHLT", true, 1, 0},
+		{"load use waiting for WB", "LOAD x1, x0, 3\nADD x2, x1, x1\nHLT", false, 2, 0},
+		{"distant producer", "INC x1\nNOP\nNOP\nADD x2, x1, x1\nHLT", false, 0, 0},
+		{"taken branch", "BRA skip\nNOP\nskip: HLT", true, 0, 2},
+		{"branch not taken", "INC x1\nBEQ x1, x0, skip\nNOP\nskip: HLT", true, 0, 0},
+	}
+
+	for _, test := range tests {
+		pipe := runPipeline(t, test.source, test.forwarding)
+		if pipe.DataStalls != test.dataStalls || pipe.ControlStalls != test.controlStalls {
+			t.Errorf("%s: expected %d data and %d control stall cycles, got %d and %d",
+				test.name, test.dataStalls, test.controlStalls, pipe.DataStalls, pipe.ControlStalls)
+		}
+	}
+}
+
+func TestTimings(t *testing.T) {
+	pipe := runPipeline(t, "LOAD x1, x0, 3\nADD x2, x1, x1\nHLT", true)
+
+	expected := [][NumStages]uint{
+		{1, 2, 3, 4, 5},
+		{2, 3, 5, 6, 7},
+		{3, 5, 6, 7, 8},
+	}
+	for i, timing := range pipe.Timings {
+		if timing.Stages != expected[i] {
+			t.Errorf("expected %s to enter stages in cycles %v, got %v", timing.Source, expected[i], timing.Stages)
+		}
+	}
+	if pipe.Cycles != 8 || pipe.Instructions != 3 {
+		t.Errorf("expected 3 instructions in 8 cycles, got %d in %d", pipe.Instructions, pipe.Cycles)
+	}
+}
+
+// Pipelining only changes timing, never what a program does
+func TestSameResults(t *testing.T) {
+	for _, name := range []string{"factorial", "reverser", "memcalc", "fastmult"} {
+		plain, _ := sim.NewComputerFile("../examples/" + name + ".ct33")
+		piped, _ := sim.NewComputerFile("../examples/" + name + ".ct33")
+		piped.Tracer = New(Options{Forwarding: true})
+		for _, comp := range []*sim.Computer{plain, piped} {
+			comp.SetInputs([]uint{3, 4, 2, 7})
+			comp.Run(10000)
+		}
+		if !reflect.DeepEqual(plain.Snapshot(), piped.Snapshot()) {
+			t.Errorf("%s: expected pipelined run to end in same state", name)
+		}
+	}
+}
+
+func TestPredictedBranches(t *testing.T) {
+	// loop going around 3 times
+	source := "LODI x1, 3\nloop: DEC x1\nBGT x1, x0, loop\nHLT"
+	program, _ := asm.Assemble(strings.NewReader(source))
+
+	for _, test := range []struct {
+		predictor      string
+		mispredictions uint
+	}{{"not-taken", 2}, {"taken", 1}} {
+		comp, _ := sim.NewComputer(program)
+		predictor, _ := predict.New(test.predictor)
+		pipe := New(Options{Forwarding: true, Predictor: predictor})
+		comp.Tracer = pipe
+		comp.Run(100)
+
+		if pipe.Mispredictions != test.mispredictions || pipe.ControlStalls != 2*test.mispredictions {
+			t.Errorf("expected %s to mispredict %d branches costing 2 cycles each, got %d costing %d",
+				test.predictor, test.mispredictions, pipe.Mispredictions, pipe.ControlStalls)
+		}
+	}
+}
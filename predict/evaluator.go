@@ -0,0 +1,159 @@
+package predict
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ordovician/calcutron/disasm"
+	"github.com/ordovician/calcutron/prog"
+	"github.com/ordovician/calcutron/sim"
+)
+
+// Conditional branch executed in record, giving where it branches to and
+// whether it was taken. BEQ and BGT with a zero offset halt rather than branch
+func Branch(record *sim.TraceRecord) (target uint, taken bool, ok bool) {
+	inst := disasm.DisassembleInstruction(record.Word)
+	opcode := inst.Opcode()
+	if opcode != prog.BEQ && opcode != prog.BGT || inst.Constant() == 0 {
+		return 0, false, false
+	}
+	target = uint(int(record.PC) + inst.Constant())
+	return target, record.NextPC != record.PC+1, true
+}
+
+// How often branches were executed, taken and mispredicted
+type Stats struct {
+	Executed     uint
+	Taken        uint
+	Mispredicted uint
+}
+
+// Percentage of branches predicted correctly
+func (stats *Stats) Accuracy() float64 {
+	if stats.Executed == 0 {
+		return 100
+	}
+	return 100 * float64(stats.Executed-stats.Mispredicted) / float64(stats.Executed)
+}
+
+func (stats *Stats) add(mispredicted, taken bool) {
+	stats.Executed++
+	if taken {
+		stats.Taken++
+	}
+	if mispredicted {
+		stats.Mispredicted++
+	}
+}
+
+// Evaluation of a single predictor
+type Evaluation struct {
+	Predictor Predictor
+	Total     Stats
+	Branches  map[uint]*Stats // stats of each branch by its address
+}
+
+// Evaluator is a tracer which lets predictors guess the outcome of every
+// conditional branch executed, and keeps track of how often they were right
+type Evaluator struct {
+	Evaluations []*Evaluation
+
+	program *prog.Program   // program as it was when evaluation started
+	labels  map[uint]string // label defined at each address of program
+}
+
+// Create evaluator comparing predictors on program loaded into computer. Set it
+// as the Tracer of the computer to evaluate them while running the program
+func NewEvaluator(comp *sim.Computer, predictors ...Predictor) *Evaluator {
+	program, _ := disasm.DisassembleMemoryWithOptions(comp.ProgramSlice(), &disasm.Options{
+		Entry:   comp.Entry(),
+		Symbols: comp.Labels(),
+	})
+	labels := make(map[uint]string)
+	for label, addr := range program.Labels {
+		if other, ok := labels[addr]; !ok || label < other {
+			labels[addr] = label
+		}
+	}
+
+	evaluator := Evaluator{program: program, labels: labels}
+	for _, predictor := range predictors {
+		evaluator.Evaluations = append(evaluator.Evaluations, &Evaluation{
+			Predictor: predictor,
+			Branches:  make(map[uint]*Stats),
+		})
+	}
+	return &evaluator
+}
+
+func (evaluator *Evaluator) Trace(record *sim.TraceRecord) {
+	target, taken, ok := Branch(record)
+	if !ok {
+		return
+	}
+
+	for _, eval := range evaluator.Evaluations {
+		mispredicted := eval.Predictor.Predict(record.PC, target) != taken
+		eval.Predictor.Update(record.PC, target, taken)
+
+		stats := eval.Branches[record.PC]
+		if stats == nil {
+			stats = &Stats{}
+			eval.Branches[record.PC] = stats
+		}
+		stats.add(mispredicted, taken)
+		eval.Total.add(mispredicted, taken)
+	}
+}
+
+// Write accuracy of every predictor, followed by a listing of the program
+// showing how many times each branch was mispredicted by each predictor
+func (evaluator *Evaluator) WriteReport(writer io.Writer) {
+	if len(evaluator.Evaluations) == 0 {
+		return
+	}
+
+	fmt.Fprintln(writer, "Predictor                            Branches  Mispredicted  Accuracy")
+	for _, eval := range evaluator.Evaluations {
+		fmt.Fprintf(writer, "%-35s  %8d  %12d  %7.1f%%\n",
+			eval.Predictor.Description(), eval.Total.Executed, eval.Total.Mispredicted, eval.Total.Accuracy())
+	}
+	fmt.Fprintln(writer)
+
+	// one column of mispredictions for each predictor
+	var header strings.Builder
+	widths := make([]int, len(evaluator.Evaluations))
+	fmt.Fprint(&header, "  Exec  Taken")
+	for i, eval := range evaluator.Evaluations {
+		widths[i] = len(eval.Predictor.Name())
+		if widths[i] < 4 {
+			widths[i] = 4
+		}
+		fmt.Fprintf(&header, "  %*s", widths[i], eval.Predictor.Name())
+	}
+	fmt.Fprintf(writer, "%s  Addr  Source\n", header.String())
+
+	for addr, inst := range evaluator.program.Instructions {
+		var columns strings.Builder
+		first := evaluator.Evaluations[0].Branches[uint(addr)]
+		if first != nil {
+			fmt.Fprintf(&columns, "%6d  %5d", first.Executed, first.Taken)
+		} else {
+			fmt.Fprintf(&columns, "%6s  %5s", "", "")
+		}
+		for i, eval := range evaluator.Evaluations {
+			if stats := eval.Branches[uint(addr)]; stats != nil {
+				fmt.Fprintf(&columns, "  %*d", widths[i], stats.Mispredicted)
+			} else {
+				fmt.Fprintf(&columns, "  %*s", widths[i], "")
+			}
+		}
+
+		label := ""
+		if name, ok := evaluator.labels[uint(addr)]; ok {
+			label = name + ":"
+		}
+		fmt.Fprintf(writer, "%s  %02d    %-12s %s\n", columns.String(), addr, label, inst.SourceCode())
+	}
+}
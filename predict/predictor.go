@@ -0,0 +1,203 @@
+// Package predict simulates branch predictors on the conditional branches
+// BEQ and BGT of a running Calcutron-33 program, showing how well each one
+// guesses whether a branch will be taken.
+//
+// Predictors range from static ones which always guess the same way, or
+// guess backward branches are taken since they usually close loops, to
+// dynamic ones which remember what each branch did the last times it
+// executed. The branch target buffer only remembers a few branches, so
+// branches sharing an entry push each other out.
+package predict
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Predictor guesses whether a conditional branch will be taken before it is
+// executed, and learns from what the branch actually did
+type Predictor interface {
+	Name() string        // short name used on the command line
+	Description() string // longer name used in reports
+	Predict(pc, target uint) bool
+	Update(pc, target uint, taken bool)
+}
+
+// Number of entries in branch target buffer unless told otherwise
+const DefaultBTBSize = 4
+
+// Names of all predictors, in order from simplest to most advanced
+var Names = []string{"not-taken", "taken", "btfn", "1bit", "2bit", "btb"}
+
+// Create predictor from its short name
+func New(name string) (Predictor, error) {
+	switch strings.ToLower(name) {
+	case "not-taken":
+		return &static{taken: false}, nil
+	case "taken":
+		return &static{taken: true}, nil
+	case "btfn":
+		return &backwardTaken{}, nil
+	case "1bit":
+		return &oneBit{history: make(map[uint]bool)}, nil
+	case "2bit":
+		return &twoBit{counters: make(map[uint]counter)}, nil
+	case "btb":
+		return NewBTB(DefaultBTBSize), nil
+	}
+	return nil, fmt.Errorf("unknown branch predictor '%s'. Use one of %s", name, strings.Join(Names, ", "))
+}
+
+// Always predicts the same
+type static struct {
+	taken bool
+}
+
+func (p *static) Name() string {
+	if p.taken {
+		return "taken"
+	}
+	return "not-taken"
+}
+
+func (p *static) Description() string {
+	if p.taken {
+		return "static taken"
+	}
+	return "static not taken"
+}
+
+func (p *static) Predict(pc, target uint) bool {
+	return p.taken
+}
+
+func (p *static) Update(pc, target uint, taken bool) {}
+
+// Predicts backward branches closing loops as taken, and forward branches skipping code as not taken
+type backwardTaken struct{}
+
+func (p *backwardTaken) Name() string {
+	return "btfn"
+}
+
+func (p *backwardTaken) Description() string {
+	return "backward taken, forward not taken"
+}
+
+func (p *backwardTaken) Predict(pc, target uint) bool {
+	return target <= pc
+}
+
+func (p *backwardTaken) Update(pc, target uint, taken bool) {}
+
+// Predicts each branch does what it did last time
+type oneBit struct {
+	history map[uint]bool
+}
+
+func (p *oneBit) Name() string {
+	return "1bit"
+}
+
+func (p *oneBit) Description() string {
+	return "1-bit last outcome"
+}
+
+func (p *oneBit) Predict(pc, target uint) bool {
+	return p.history[pc]
+}
+
+func (p *oneBit) Update(pc, target uint, taken bool) {
+	p.history[pc] = taken
+}
+
+// Saturating counter from 0 to 3, where 2 and 3 predict taken. A branch has
+// to go the other way twice in a row to change the prediction, so a loop
+// exiting once does not cause a misprediction when it is entered again
+type counter uint8
+
+func (c counter) taken() bool {
+	return c >= 2
+}
+
+func (c counter) update(taken bool) counter {
+	if taken && c < 3 {
+		return c + 1
+	} else if !taken && c > 0 {
+		return c - 1
+	}
+	return c
+}
+
+// Counter of branch not seen before, predicting not taken until it is taken once
+const weaklyNotTaken counter = 1
+
+// Predicts with a 2-bit saturating counter for each branch
+type twoBit struct {
+	counters map[uint]counter
+}
+
+func (p *twoBit) counter(pc uint) counter {
+	if c, ok := p.counters[pc]; ok {
+		return c
+	}
+	return weaklyNotTaken
+}
+
+func (p *twoBit) Name() string {
+	return "2bit"
+}
+
+func (p *twoBit) Description() string {
+	return "2-bit saturating counter"
+}
+
+func (p *twoBit) Predict(pc, target uint) bool {
+	return p.counter(pc).taken()
+}
+
+func (p *twoBit) Update(pc, target uint, taken bool) {
+	p.counters[pc] = p.counter(pc).update(taken)
+}
+
+// Remembered branch in branch target buffer
+type btbEntry struct {
+	valid  bool
+	pc     uint
+	target uint
+	count  counter
+}
+
+// BTB is a branch target buffer remembering the target and a 2-bit counter
+// for a few taken branches. Branches are placed in entry pc modulo its size,
+// pushing out any other branch there. Branches not in the buffer are
+// predicted not taken
+type BTB struct {
+	entries []btbEntry
+}
+
+func NewBTB(size int) *BTB {
+	return &BTB{entries: make([]btbEntry, size)}
+}
+
+func (p *BTB) Name() string {
+	return "btb"
+}
+
+func (p *BTB) Description() string {
+	return fmt.Sprintf("%d entry branch target buffer", len(p.entries))
+}
+
+func (p *BTB) Predict(pc, target uint) bool {
+	entry := p.entries[pc%uint(len(p.entries))]
+	return entry.valid && entry.pc == pc && entry.target == target && entry.count.taken()
+}
+
+func (p *BTB) Update(pc, target uint, taken bool) {
+	entry := &p.entries[pc%uint(len(p.entries))]
+	if entry.valid && entry.pc == pc {
+		entry.count = entry.count.update(taken)
+	} else if taken {
+		*entry = btbEntry{valid: true, pc: pc, target: target, count: 2}
+	}
+}
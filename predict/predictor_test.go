@@ -0,0 +1,99 @@
+package predict
+
+import (
+	"testing"
+
+	"github.com/ordovician/calcutron/sim"
+)
+
+// Count mispredictions of branch at pc to target with given outcomes
+func mispredictions(predictor Predictor, pc, target uint, outcomes string) int {
+	count := 0
+	for _, outcome := range outcomes {
+		taken := outcome == 'T'
+		if predictor.Predict(pc, target) != taken {
+			count++
+		}
+		predictor.Update(pc, target, taken)
+	}
+	return count
+}
+
+func TestPredictors(t *testing.T) {
+	// a loop going around three times, entered twice
+	loop := "TTNTTN"
+
+	tests := []struct {
+		name     string
+		backward uint // mispredictions when branch jumps backwards
+		forward  uint // mispredictions when branch jumps forward
+	}{
+		{"not-taken", 4, 4},
+		{"taken", 2, 2},
+		{"btfn", 2, 4},
+		{"1bit", 4, 4},
+		{"2bit", 3, 3},
+		{"btb", 3, 3},
+	}
+
+	for _, test := range tests {
+		predictor, err := New(test.name)
+		if err != nil {
+			t.Fatalf("failed to create %s predictor because %v", test.name, err)
+		}
+		if n := mispredictions(predictor, 10, 5, loop); n != int(test.backward) {
+			t.Errorf("expected %s to mispredict backward branch %d times, got %d", predictor.Description(), test.backward, n)
+		}
+
+		predictor, _ = New(test.name)
+		if n := mispredictions(predictor, 10, 15, loop); n != int(test.forward) {
+			t.Errorf("expected %s to mispredict forward branch %d times, got %d", predictor.Description(), test.forward, n)
+		}
+	}
+
+	if _, err := New("perfect"); err == nil {
+		t.Errorf("expected unknown predictor to be an error")
+	}
+}
+
+func TestBTBConflict(t *testing.T) {
+	// branches at 1 and 3 share an entry in a BTB of 2 entries, so alternating
+	// between them means neither is ever found
+	btb := NewBTB(2)
+	count := 0
+	for i := 0; i < 4; i++ {
+		count += mispredictions(btb, 1, 0, "T")
+		count += mispredictions(btb, 3, 0, "T")
+	}
+	if count != 8 {
+		t.Errorf("expected every branch to be mispredicted, got %d of 8", count)
+	}
+
+	btb = NewBTB(4)
+	count = 0
+	for i := 0; i < 4; i++ {
+		count += mispredictions(btb, 1, 0, "T")
+		count += mispredictions(btb, 3, 0, "T")
+	}
+	if count != 2 {
+		t.Errorf("expected only first time of each branch to be mispredicted, got %d", count)
+	}
+}
+
+func TestEvaluator(t *testing.T) {
+	comp, _ := sim.NewComputerFile("../examples/simplemult.ct33")
+	comp.SetInputs([]uint{3, 4})
+	notTaken, _ := New("not-taken")
+	twoBit, _ := New("2bit")
+	evaluator := NewEvaluator(comp, notTaken, twoBit)
+	comp.Tracer = evaluator
+	comp.Run(100)
+
+	// BGT x2, x0, multiply at 05 is taken three times and then falls through
+	expected := []Stats{{4, 3, 3}, {4, 3, 2}}
+	for i, eval := range evaluator.Evaluations {
+		if eval.Total != expected[i] || len(eval.Branches) != 1 || *eval.Branches[5] != expected[i] {
+			t.Errorf("expected %s to give %+v, got %+v", eval.Predictor.Description(), expected[i], eval.Total)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package profile
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Event in Chrome's trace event format, as used by chrome://tracing and Perfetto
+type traceEvent struct {
+	Name      string `json:"name,omitempty"`
+	Phase     string `json:"ph"` // B begins and E ends a duration
+	Timestamp uint   `json:"ts"`
+	Process   int    `json:"pid"`
+	Thread    int    `json:"tid"`
+}
+
+type chromeTrace struct {
+	TraceEvents []traceEvent `json:"traceEvents"`
+}
+
+// Write subroutine calls as Chrome trace event JSON, showing a timeline of
+// calls with main at the bottom. Time is measured in executed instructions,
+// shown by the viewer as one microsecond per instruction
+func (profile *Profile) WriteChromeTrace(writer io.Writer) error {
+	main, ok := profile.labels[profile.program.Entry]
+	if !ok {
+		main = "main"
+	}
+	events := []traceEvent{{Name: main, Phase: "B", Timestamp: 0, Process: 1, Thread: 1}}
+	depth := 0
+
+	for _, call := range profile.calls {
+		if call.isCall {
+			events = append(events, traceEvent{Name: profile.name(call.dest, "sub_"), Phase: "B", Timestamp: call.step, Process: 1, Thread: 1})
+			depth++
+		} else if depth > 0 {
+			// the return is the last instruction of the subroutine
+			events = append(events, traceEvent{Phase: "E", Timestamp: call.step, Process: 1, Thread: 1})
+			depth--
+		}
+	}
+
+	// subroutines still running when program stopped
+	for ; depth >= 0; depth-- {
+		events = append(events, traceEvent{Phase: "E", Timestamp: profile.Steps, Process: 1, Thread: 1})
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(chromeTrace{TraceEvents: events})
+}
@@ -0,0 +1,150 @@
+// Package profile measures where a running Calcutron-33 program spends its
+// time, to give objective numbers when optimizing programs.
+//
+// A Profile is a tracer which counts how many times every address is executed,
+// how often each instruction is used, which memory words are read and written
+// and how many times every loop goes around. Loops are found from back edges,
+// which are branches and jumps to an earlier address. Subroutine calls and
+// returns are recorded so they can be viewed as a timeline in Chrome's trace
+// viewer or Perfetto.
+package profile
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ordovician/calcutron/disasm"
+	"github.com/ordovician/calcutron/prog"
+	"github.com/ordovician/calcutron/sim"
+)
+
+// Number of times a loop was executed
+type loop struct {
+	head       uint // address jumped back to
+	tail       uint // last address jumping back to head
+	iterations uint
+}
+
+// Subroutine call or return at a given step
+type callEvent struct {
+	step   uint
+	dest   uint // address called, unused for returns
+	isCall bool
+}
+
+type Profile struct {
+	Steps        uint            // number of instructions executed
+	Cycles       uint            // clock cycles taken by executed instructions
+	Counts       map[uint]uint   // times each address was executed
+	Opcodes      map[string]uint // times each base instruction was executed
+	PseudoCodes  map[string]uint // times each instruction was executed, with pseudo instructions recognized
+	MemoryReads  map[uint]uint   // times each memory address was read
+	MemoryWrites map[uint]uint   // times each memory address was written
+
+	loops   map[uint]*loop
+	calls   []callEvent
+	program *prog.Program   // program as it was when profiling started
+	labels  map[uint]string // label defined at each address of program
+}
+
+// Create profile for program loaded into computer. Set it as the Tracer of
+// the computer to profile running the program
+func New(comp *sim.Computer) *Profile {
+	program, _ := disasm.DisassembleMemoryWithOptions(comp.ProgramSlice(), &disasm.Options{
+		Entry:   comp.Entry(),
+		Symbols: comp.Labels(),
+	})
+
+	labels := make(map[uint]string)
+	for label, addr := range program.Labels {
+		if other, ok := labels[addr]; !ok || label < other {
+			labels[addr] = label
+		}
+	}
+
+	return &Profile{
+		Counts:       make(map[uint]uint),
+		Opcodes:      make(map[string]uint),
+		PseudoCodes:  make(map[string]uint),
+		MemoryReads:  make(map[uint]uint),
+		MemoryWrites: make(map[uint]uint),
+		loops:        make(map[uint]*loop),
+		program:      program,
+		labels:       labels,
+	}
+}
+
+func (profile *Profile) Trace(record *sim.TraceRecord) {
+	// halting or running out of input leaves the instruction unfinished, so
+	// it is not counted as a step, just like the computer does
+	if record.NextPC == record.PC {
+		return
+	}
+	profile.Steps++
+	profile.Cycles += record.Cycles
+	profile.Counts[record.PC]++
+	for _, read := range record.MemoryReads {
+		profile.MemoryReads[read.Address]++
+	}
+	for _, write := range record.MemoryWrites {
+		profile.MemoryWrites[write.Address]++
+	}
+
+	inst := disasm.DisassembleInstruction(record.Word)
+	profile.Opcodes[inst.Opcode().String()]++
+	profile.PseudoCodes[prog.RecognizePseudo(inst).PseudoCode().String()]++
+
+	rd := record.Word / 100 % 10
+	k := inst.Constant()
+	switch {
+	case inst.Opcode() == prog.JMP && rd != 0 && k == 0:
+		profile.calls = append(profile.calls, callEvent{step: profile.Steps})
+	case inst.Opcode() == prog.JMP && rd != 0:
+		profile.calls = append(profile.calls, callEvent{step: profile.Steps, dest: record.NextPC, isCall: true})
+	case record.NextPC < record.PC:
+		lp := profile.loops[record.NextPC]
+		if lp == nil {
+			lp = &loop{head: record.NextPC}
+			profile.loops[record.NextPC] = lp
+		}
+		lp.iterations++
+		if record.PC > lp.tail {
+			lp.tail = record.PC
+		}
+	}
+}
+
+// Label at addr, or an address based name if there is none
+func (profile *Profile) name(addr uint, prefix string) string {
+	if label, ok := profile.labels[addr]; ok {
+		return label
+	}
+	return fmt.Sprintf("%s%02d", prefix, addr)
+}
+
+func (profile *Profile) percent(count uint) float64 {
+	if profile.Steps == 0 {
+		return 0
+	}
+	return 100 * float64(count) / float64(profile.Steps)
+}
+
+// Steps spent inside loop, counting every instruction between its head and tail
+func (profile *Profile) loopSteps(lp *loop) uint {
+	var steps uint
+	for addr := lp.head; addr <= lp.tail; addr++ {
+		steps += profile.Counts[addr]
+	}
+	return steps
+}
+
+// Write report with an annotated listing, hottest loops, instruction mix and
+// memory accesses
+func (profile *Profile) WriteReport(writer io.Writer) {
+	fmt.Fprintf(writer, "Profile of %d executed instructions taking %d cycles\n\n", profile.Steps, profile.Cycles)
+	profile.writeListing(writer)
+	profile.writeLoops(writer)
+	profile.writeMix(writer, "Instruction mix", profile.Opcodes)
+	profile.writeMix(writer, "Instruction mix with pseudo instructions", profile.PseudoCodes)
+	profile.writeMemory(writer)
+}
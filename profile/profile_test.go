@@ -0,0 +1,112 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ordovician/calcutron/sim"
+)
+
+func runProfile(t *testing.T, filepath string, inputs ...uint) *Profile {
+	comp, err := sim.NewComputerFile(filepath)
+	if err != nil {
+		t.Fatalf("failed to load program because %v", err)
+	}
+	comp.SetInputs(inputs)
+	profile := New(comp)
+	comp.Tracer = profile
+	comp.Run(10000)
+	return profile
+}
+
+func TestProfileCounts(t *testing.T) {
+	// multiplies 3 by 4 by adding 3 four times, then stops when reading more
+	// input, which does not count as executing address 00 again
+	profile := runProfile(t, "../examples/simplemult.ct33", 3, 4)
+
+	expected := map[uint]uint{0: 1, 1: 1, 2: 1, 3: 4, 4: 4, 5: 4, 6: 1, 7: 1}
+	for addr, count := range expected {
+		if profile.Counts[addr] != count {
+			t.Errorf("expected address %02d to execute %d times, got %d", addr, count, profile.Counts[addr])
+		}
+	}
+	if profile.Steps != 17 {
+		t.Errorf("expected 17 steps, got %d", profile.Steps)
+	}
+	if profile.PseudoCodes["DEC"] != 4 || profile.Opcodes["ADDI"] != 4 {
+		t.Errorf("expected DEC to be counted as pseudo instruction and ADDI as base instruction, got %v and %v", profile.PseudoCodes, profile.Opcodes)
+	}
+
+	lp := profile.loops[3]
+	if lp == nil || lp.tail != 5 || lp.iterations != 3 || profile.loopSteps(lp) != 12 {
+		t.Errorf("expected loop from 03 to 05 going around 3 times, got %+v", lp)
+	}
+}
+
+func TestProfileSteps(t *testing.T) {
+	comp, err := sim.NewComputerFile("../examples/memadder.ct33")
+	if err != nil {
+		t.Fatalf("failed to load program because %v", err)
+	}
+	profile := New(comp)
+	comp.Tracer = profile
+	if result := comp.Run(100); result.Reason != sim.Halted {
+		t.Fatalf("expected program to halt, got %v", result.Reason)
+	}
+
+	// HLT stops the program without being counted as a step
+	if steps := comp.Snapshot().Steps; profile.Steps != steps || steps != 4 {
+		t.Errorf("expected profile to count the 4 steps of the computer, got %d and %d", profile.Steps, steps)
+	}
+	if profile.Counts[4] != 0 || profile.PseudoCodes["HLT"] != 0 {
+		t.Errorf("expected HLT at 04 not to be counted, got %d", profile.Counts[4])
+	}
+}
+
+func TestProfileMemory(t *testing.T) {
+	profile := runProfile(t, "../examples/memadder.ct33")
+	if profile.MemoryReads[5] != 1 || profile.MemoryReads[6] != 1 || profile.MemoryWrites[7] != 1 {
+		t.Errorf("expected first and second to be read and result to be written, got %v and %v", profile.MemoryReads, profile.MemoryWrites)
+	}
+
+	var report bytes.Buffer
+	profile.WriteReport(&report)
+	if !strings.Contains(report.String(), "  07         0       1  result") {
+		t.Errorf("expected report to show write to result, got\n%s", report.String())
+	}
+}
+
+func TestChromeTrace(t *testing.T) {
+	profile := runProfile(t, "../examples/factorial.ct33", 3)
+
+	var buffer bytes.Buffer
+	if err := profile.WriteChromeTrace(&buffer); err != nil {
+		t.Fatalf("failed to write Chrome trace because %v", err)
+	}
+	var trace chromeTrace
+	if err := json.Unmarshal(buffer.Bytes(), &trace); err != nil {
+		t.Fatalf("failed to read Chrome trace because %v", err)
+	}
+
+	// main calls multiply twice, which calls multdigit once for each digit
+	var names []string
+	depth := 0
+	for _, event := range trace.TraceEvents {
+		switch event.Phase {
+		case "B":
+			names = append(names, event.Name)
+			depth++
+		case "E":
+			depth--
+		}
+		if depth < 0 {
+			t.Fatalf("more durations ended than begun in %v", trace.TraceEvents)
+		}
+	}
+	expected := "main multiply multdigit multiply multdigit"
+	if strings.Join(names, " ") != expected || depth != 0 {
+		t.Errorf("expected calls %s, got %v ending at depth %d", expected, names, depth)
+	}
+}
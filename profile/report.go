@@ -0,0 +1,114 @@
+package profile
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// Widest bar drawn in instruction mix histograms
+const barWidth = 40
+
+// Most loops shown in report
+const maxLoops = 10
+
+// Every instruction of program with the number of times it was executed and
+// its share of all executed instructions
+func (profile *Profile) writeListing(writer io.Writer) {
+	fmt.Fprintln(writer, " Count       %  Addr  Source")
+	for addr, inst := range profile.program.Instructions {
+		count, percent := "-", ""
+		if n := profile.Counts[uint(addr)]; n > 0 {
+			count = fmt.Sprint(n)
+			percent = fmt.Sprintf("%.1f%%", profile.percent(n))
+		}
+
+		label := ""
+		if name, ok := profile.labels[uint(addr)]; ok {
+			label = name + ":"
+		}
+		fmt.Fprintf(writer, "%6s  %6s  %02d    %-12s %s\n", count, percent, addr, label, inst.SourceCode())
+	}
+	fmt.Fprintln(writer)
+}
+
+// Loops sorted with those executing the most instructions first
+func (profile *Profile) writeLoops(writer io.Writer) {
+	fmt.Fprintln(writer, "Hottest loops")
+	if len(profile.loops) == 0 {
+		fmt.Fprintln(writer, "  none")
+		fmt.Fprintln(writer)
+		return
+	}
+
+	loops := maps.Values(profile.loops)
+	slices.SortFunc(loops, func(a, b *loop) bool {
+		stepsA, stepsB := profile.loopSteps(a), profile.loopSteps(b)
+		if stepsA != stepsB {
+			return stepsA > stepsB
+		}
+		return a.head < b.head
+	})
+	if len(loops) > maxLoops {
+		loops = loops[:maxLoops]
+	}
+
+	fmt.Fprintln(writer, "  Head          Lines   Iterations   Steps       %")
+	for _, lp := range loops {
+		steps := profile.loopSteps(lp)
+		fmt.Fprintf(writer, "  %-12s  %02d-%02d  %10d  %6d  %5.1f%%\n",
+			profile.name(lp.head, "loop_"), lp.head, lp.tail, lp.iterations, steps, profile.percent(steps))
+	}
+	fmt.Fprintln(writer)
+}
+
+// Histogram of how often each instruction was executed, most used first
+func (profile *Profile) writeMix(writer io.Writer, title string, counts map[string]uint) {
+	fmt.Fprintln(writer, title)
+
+	mnemonics := maps.Keys(counts)
+	slices.SortFunc(mnemonics, func(a, b string) bool {
+		if counts[a] != counts[b] {
+			return counts[a] > counts[b]
+		}
+		return a < b
+	})
+
+	var most uint
+	for _, count := range counts {
+		if count > most {
+			most = count
+		}
+	}
+	for _, mnemonic := range mnemonics {
+		count := counts[mnemonic]
+		bar := strings.Repeat("#", int((count*barWidth+most-1)/most))
+		fmt.Fprintf(writer, "  %-4s  %6d  %5.1f%%  %s\n", mnemonic, count, profile.percent(count), bar)
+	}
+	fmt.Fprintln(writer)
+}
+
+// Number of reads and writes of every memory address accessed
+func (profile *Profile) writeMemory(writer io.Writer) {
+	fmt.Fprintln(writer, "Memory accesses")
+
+	addresses := maps.Keys(profile.MemoryReads)
+	for addr := range profile.MemoryWrites {
+		if _, ok := profile.MemoryReads[addr]; !ok {
+			addresses = append(addresses, addr)
+		}
+	}
+	if len(addresses) == 0 {
+		fmt.Fprintln(writer, "  none")
+		return
+	}
+	slices.Sort(addresses)
+
+	fmt.Fprintln(writer, "  Addr   Reads  Writes")
+	for _, addr := range addresses {
+		fmt.Fprintf(writer, "  %02d    %6d  %6d  %s\n", addr, profile.MemoryReads[addr], profile.MemoryWrites[addr], profile.labels[addr])
+	}
+}
@@ -1,9 +1,6 @@
 package prog
 
-import (
-	"fmt"
-	"math"
-)
+import "math"
 
 type AddInstruction struct {
 	BaseInstruction
@@ -28,11 +25,8 @@ func (inst *AddImmediateInstruction) Run(comp Machine) bool {
 // Base implementation is setup for unsigned numbers such as JMP
 func (inst *AddImmediateInstruction) ParseOperands(labels SymbolTable, operands []string, address uint) {
 	inst.BaseInstruction.ParseOperands(labels, operands, address)
-	if inst.err != nil {
-		return
-	}
 	if inst.constant > 49 || inst.constant < -50 {
-		inst.err = fmt.Errorf("constant %d is outside valid range -50 to 49", inst.constant)
+		inst.err = inst.rangeError()
 	}
 }
 
@@ -56,7 +50,7 @@ func (inst *ShiftInstruction) ParseOperands(labels SymbolTable, operands []strin
 	}
 
 	// if shift isn't given, use 1 as the shift
-	if inst.constant == 0 {
+	if len(inst.parsedRegIndicies) == len(operands) {
 		inst.constant = 1
 	}
 }
@@ -14,6 +14,7 @@ type DataInstruction struct {
 func (inst *DataInstruction) ParseOperands(labels SymbolTable, operands []string, address uint) {
 	if len(operands) != 1 {
 		inst.err = fmt.Errorf("DAT directives can only have a single data point, not %d", len(operands))
+		return
 	}
 
 	data := operands[0]
@@ -26,6 +27,12 @@ func (inst *DataInstruction) ParseOperands(labels SymbolTable, operands []string
 	}
 }
 
+// Unlike instructions the whole 4-digit word is the data value
+func (inst *DataInstruction) DecodeOperands(word uint) {
+	inst.constant = Signed(word, 1e4)
+	inst.decoded = true
+}
+
 func (inst *DataInstruction) AssignRegisters() {
 	if inst.err != nil {
 		return
@@ -47,7 +54,7 @@ func (inst *DataInstruction) MachineCode() uint {
 
 func (inst *DataInstruction) printSourceCode(writer io.Writer) {
 	printMnemonic(writer, inst.pseudoCode)
-	NumberColor.Fprintf(writer, "%04d", inst.constant)
+	NumberColor.Fprintf(writer, "%d", inst.constant)
 }
 
 func (inst *DataInstruction) SourceCode() string {
@@ -0,0 +1,46 @@
+package prog
+
+// Describes how the operands of an instruction are encoded in the three
+// lowest digits of a 4-digit machine code word
+type Encoding uint8
+
+const (
+	RegEncoding      Encoding = iota // ?dab - three registers
+	ShortImmEncoding                 // ?dak - two registers and a single digit constant
+	LongImmEncoding                  // ?dkk - one register and a two digit constant
+	DataEncoding                     // kkkk - not an instruction but a data word
+)
+
+// Encoding used by instructions with given opcode. Pseudo instructions use
+// the encoding of the core instruction they are translated into
+func (opcode Opcode) Encoding() Encoding {
+	switch opcode {
+	case ADD, SUB, MOVE, CLR, NOP:
+		return RegEncoding
+	case ADDI, LODI, JMP, DEC, INC, SUBI, CALL:
+		return LongImmEncoding
+	case DAT, STR:
+		return DataEncoding
+	default:
+		return ShortImmEncoding
+	}
+}
+
+// Range of valid values for the constant k of an instruction with given opcode.
+// Instructions without a constant return 0, 0
+func (opcode Opcode) ConstantRange() (min, max int) {
+	switch opcode {
+	case ADD, SUB, MOVE, CLR, NOP:
+		return 0, 0
+	case LOAD, STOR, INP, OUT:
+		return -2, 7
+	case JMP, CALL:
+		return 0, 99
+	case ADDI, LODI, DEC, INC, SUBI:
+		return -50, 49
+	case DAT, STR:
+		return -5000, 9999
+	default:
+		return -5, 4
+	}
+}
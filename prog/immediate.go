@@ -23,11 +23,26 @@ func (inst *LongImmInstruction) AssignRegisters() {
 	}
 }
 
+// Error for a constant outside the range of a two digit immediate value, with
+// a suggestion to split the constant over several instructions
+func (inst *LongImmInstruction) rangeError() error {
+	err := fmt.Errorf("constant %d is outside valid range -50 to 49", inst.constant)
+	if len(inst.parsedRegIndicies) != 1 || inst.label != "" {
+		return err
+	}
+	fix, ok := splitImmediate(inst.pseudoCode, inst.parsedRegIndicies[0], inst.constant)
+	if !ok {
+		return err
+	}
+	return &FixableError{Err: err, Suggestions: []Suggestion{fix}}
+}
+
 func (inst *LongImmInstruction) DecodeOperands(operands uint) {
 	addr := operands % 100
 
 	inst.regIndicies[Rd] = uint(operands / 100)
 	inst.constant = Signed(addr, 100)
+	inst.decoded = true
 }
 
 func (inst *LongImmInstruction) MachineCode() uint {
@@ -78,15 +93,22 @@ func (inst *ShortImmInstruction) AssignRegisters() {
 }
 
 func (inst *ShortImmInstruction) printSourceCode(writer io.Writer) {
-	// NOTE: A bit of a hack to treat infinite branches as HLT operation
-	if (inst.opcode == BEQ || inst.opcode == BGT) && inst.constant == 0 {
+	// NOTE: A bit of a hack to treat infinite branches as HLT operation.
+	// Only the canonical 0000 form is shown as HLT, so it assembles back to the same word
+	if inst.MachineCode() == 0 {
 		printMnemonic(writer, HLT)
 		return
 	}
 	printMnemonic(writer, inst.opcode)
 	printRegisterOperands(writer, inst.regIndicies[0:2])
 	fmt.Fprintf(writer, ", ")
-	NumberColor.Fprintf(writer, "%d", inst.constant)
+
+	// assembled branches keep showing the relative address the label was turned into
+	if inst.decoded && inst.label != "" {
+		LabelColor.Fprintf(writer, "%s", inst.label)
+	} else {
+		NumberColor.Fprintf(writer, "%d", inst.constant)
+	}
 }
 
 // Will return colorized source code but this can be turned off with
@@ -107,6 +129,7 @@ func (inst *ShortImmInstruction) DecodeOperands(operands uint) {
 	inst.regIndicies[Rd] = uint(operands / 100)
 	inst.regIndicies[Ra] = uint(addr / 10)
 	inst.constant = Signed(addr%10, 10)
+	inst.decoded = true
 }
 
 func (inst *ShortImmInstruction) MachineCode() uint {
@@ -135,5 +158,11 @@ func (inst *ShortImmInstruction) ParseOperands(labels SymbolTable, operands []st
 
 	if inst.constant < -5 || inst.constant > 4 {
 		inst.err = fmt.Errorf("constant %d is outside valid range -5 to 4. Can happen if you try to jump to a label too far away with a branch instruction", inst.constant)
+		if inst.label == "" {
+			return
+		}
+		if fix, ok := longBranch(inst.pseudoCode, inst.parsedRegIndicies, inst.label); ok {
+			inst.err = &FixableError{Err: inst.err, Suggestions: []Suggestion{fix}}
+		}
 	}
 }
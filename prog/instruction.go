@@ -27,8 +27,6 @@ type Machine interface {
 	SetPC(address uint)
 	Memory(address uint) uint
 	SetMemory(address uint, value uint)
-	PopInput() (int, bool)
-	PushOutput(value int)
 	//LookupSymbol(sym string) (address uint, found bool)
 }
 
@@ -37,6 +35,10 @@ type Instruction interface {
 	setPseudoCode(pseudoCode Opcode)
 
 	Opcode() Opcode
+	PseudoCode() Opcode
+	Constant() int
+	Label() string
+	SetLabel(label string)
 	UniqueRegisters() []uint
 	Run(comp Machine) bool
 	MachineCode() uint
@@ -54,6 +56,7 @@ type BaseInstruction struct {
 	regIndicies [3]uint // machine code would set this directly
 	constant    int     // signed constant. How to convert this depends on whether we deal with single of double digit constant
 	label       string
+	decoded     bool // created from machine code rather than source code
 
 	parsedRegIndicies []uint // set from parsed source code
 	err               error  // sticky error
@@ -71,6 +74,27 @@ func (inst *BaseInstruction) Opcode() Opcode {
 	return inst.opcode
 }
 
+// The mnemonic used in source code. Differs from Opcode for pseudo instructions
+// and DAT directives
+func (inst *BaseInstruction) PseudoCode() Opcode {
+	return inst.pseudoCode
+}
+
+// The signed constant k of the instruction, or data value of a DAT directive
+func (inst *BaseInstruction) Constant() int {
+	return inst.constant
+}
+
+// Label used as operand, or given to the destination of a decoded branch or jump
+func (inst *BaseInstruction) Label() string {
+	return inst.label
+}
+
+// Refer to destination of a branch or jump by label when showing source code
+func (inst *BaseInstruction) SetLabel(label string) {
+	inst.label = label
+}
+
 func (inst *BaseInstruction) UniqueRegisters() []uint {
 	return utils.RemoveDuplicates(inst.regIndicies[:])
 }
@@ -122,6 +146,11 @@ func (inst *BaseInstruction) String() string {
 // Fills in the parsedRegIndicies, constant and label fields
 // operands should not contain uncessesary whitespace. Caller is responsible for cleaning up operands before calling
 // ParseOperands
+//
+// An operand which is neither a register, a number nor a known label is an
+// error, rather than being skipped. A constant outside of -50 to 99 is also an
+// error, but the remaining operands are still parsed so that the instruction
+// knows its registers when suggesting a fix
 func (inst *BaseInstruction) ParseOperands(labels SymbolTable, operands []string, address uint) {
 	registers := make([]uint, 0)
 
@@ -130,12 +159,13 @@ func (inst *BaseInstruction) ParseOperands(labels SymbolTable, operands []string
 			inst.constant = int(addr)
 			inst.label = operand
 		} else if constant, err := strconv.Atoi(operand); err == nil {
+			// keep parsing remaining operands, so instructions with a narrower
+			// range can suggest how to fix the error
 			if constant < -50 || constant > 99 {
 				inst.err = fmt.Errorf("constant %d is outside valid range -50 to 99", constant)
-				return
 			}
 			inst.constant = constant
-		} else if len(operand) > 0 && operand[0] == 'x' {
+		} else if len(operand) > 0 && (operand[0] == 'x' || operand[0] == 'X') {
 			i, err := strconv.Atoi(operand[1:])
 			if err != nil {
 				inst.err = fmt.Errorf("unable to parse index %s because %w", operand[1:], err)
@@ -146,6 +176,9 @@ func (inst *BaseInstruction) ParseOperands(labels SymbolTable, operands []string
 				return
 			}
 			registers = append(registers, uint(i))
+		} else {
+			inst.err = fmt.Errorf("'%s' is not a register, number or known label", operand)
+			return
 		}
 	}
 	inst.parsedRegIndicies = registers
@@ -163,13 +196,14 @@ func (inst *BaseInstruction) AssignRegisters() {
 	if inst.err != nil {
 		return
 	}
-	inst.regIndicies[Rd] = inst.parsedRegIndicies[0]
 	n := len(inst.parsedRegIndicies)
 	switch n {
 	case 2:
+		inst.regIndicies[Rd] = inst.parsedRegIndicies[0]
 		inst.regIndicies[Ra] = inst.parsedRegIndicies[0]
 		inst.regIndicies[Rb] = inst.parsedRegIndicies[1]
 	case 3:
+		inst.regIndicies[Rd] = inst.parsedRegIndicies[0]
 		inst.regIndicies[Ra] = inst.parsedRegIndicies[1]
 		inst.regIndicies[Rb] = inst.parsedRegIndicies[2]
 	default:
@@ -183,6 +217,7 @@ func (inst *BaseInstruction) DecodeOperands(operands uint) {
 	inst.regIndicies[Rd] = uint(operands / 100)
 	inst.regIndicies[Ra] = uint(addr / 10)
 	inst.regIndicies[Rb] = uint(addr % 10)
+	inst.decoded = true
 }
 
 func (inst *BaseInstruction) Error() error {
@@ -31,6 +31,14 @@ func (inst *JumpInstruction) Run(comp Machine) bool {
 	return true
 }
 
+// Unlike other instructions with a two digit constant, the destination
+// address of a jump is not signed
+func (inst *JumpInstruction) DecodeOperands(operands uint) {
+	inst.regIndicies[Rd] = uint(operands / 100)
+	inst.constant = int(operands % 100)
+	inst.decoded = true
+}
+
 func (inst *JumpInstruction) ParseOperands(labels SymbolTable, operands []string, address uint) {
 	inst.BaseInstruction.ParseOperands(labels, operands, address)
 	if inst.err != nil {
@@ -38,6 +38,7 @@ func (inst *LoadStoreInstruction) DecodeOperands(operands uint) {
 	if inst.constant >= 8 {
 		inst.constant = inst.constant - 10
 	}
+	inst.decoded = true
 }
 
 func (inst *LoadStoreInstruction) MachineCode() uint {
@@ -89,20 +90,10 @@ type LoadInstruction struct {
 }
 
 func (inst *LoadInstruction) Run(comp Machine) bool {
-	signedAddr := inst.RegValue(comp, Ra) + inst.constant
-	addr := Complement(signedAddr, 1e4)
-
-	if signedAddr == -1 {
-		// check if we have exhausted input. Program should terminate in that case
-		value, ok := comp.PopInput()
-		if !ok {
-			return false
-		}
-		inst.SetRegValue(comp, Rd, value)
-	} else {
-		value := Signed(comp.Memory(addr), 1e4)
-		inst.SetRegValue(comp, Rd, value)
-	}
+	// reading address -1 gets input from the tape device
+	addr := Complement(inst.RegValue(comp, Ra)+inst.constant, 1e4)
+	value := Signed(comp.Memory(addr), 1e4)
+	inst.SetRegValue(comp, Rd, value)
 	return true
 }
 
@@ -117,11 +108,8 @@ func (inst *MoveInstruction) Run(comp Machine) bool {
 
 func (inst *MoveInstruction) ParseOperands(labels SymbolTable, operands []string, address uint) {
 	inst.BaseInstruction.ParseOperands(labels, operands, address)
-	if inst.err != nil {
-		return
-	}
 	if inst.constant > 49 || inst.constant < -50 {
-		inst.err = fmt.Errorf("constant %d is outside valid range -50 to 49", inst.constant)
+		inst.err = inst.rangeError()
 	}
 }
 
@@ -130,13 +118,9 @@ type StoreInstruction struct {
 }
 
 func (inst *StoreInstruction) Run(comp Machine) bool {
+	// writing address -1 outputs to the tape device
 	address := inst.RegValue(comp, Ra) + inst.constant
 	value := inst.RegValue(comp, Rd)
-
-	if address == -1 {
-		comp.PushOutput(value)
-	} else {
-		comp.SetMemory(Complement(address, 1e4), Complement(value, 1e4))
-	}
+	comp.SetMemory(Complement(address, 1e4), Complement(value, 1e4))
 	return true
 }
@@ -0,0 +1,80 @@
+package prog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Version of machine code file format written by WriteMachineFile. A machine
+// code file may start with a header of lines such as:
+//
+//	#version 1
+//	#name adder
+//	#entry 0
+//	#symbol loop 0
+//	#checksum 00230014
+//
+// followed by 4-digit machine code words. Comments start with // and go to the
+// end of the line. Files without a header are plain streams of 4-digit words.
+const MachineFileVersion = 1
+
+// Modulus used by Checksum. The largest prime below 10000
+const checksumModulus = 9973
+
+// Fletcher style checksum of machine code words, which unlike a plain sum also
+// detects words being swapped. Written as 8 digits in machine code file headers
+func Checksum(words []uint) uint {
+	var sum, weighted uint
+	for _, word := range words {
+		sum = (sum + word) % checksumModulus
+		weighted = (weighted + sum) % checksumModulus
+	}
+	return weighted*10000 + sum
+}
+
+// Machine code words of program
+func (prog *Program) MachineCode() []uint {
+	words := make([]uint, len(prog.Instructions))
+	for i, inst := range prog.Instructions {
+		words[i] = inst.MachineCode()
+	}
+	return words
+}
+
+// WriteMachineFile writes program as machine code with a header giving name,
+// entry point, symbols and checksum of program, one word per line
+func (prog *Program) WriteMachineFile(writer io.Writer) error {
+	words := prog.MachineCode()
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "#version %d\n", MachineFileVersion)
+	if prog.Name != "" {
+		fmt.Fprintf(&builder, "#name %s\n", prog.Name)
+	}
+	fmt.Fprintf(&builder, "#entry %d\n", prog.Entry)
+
+	// dot labels are offsets rather than addresses and IO labels are always defined
+	ioLabels := make(SymbolTable)
+	ioLabels.AddIOLabels()
+	symbols := make([]string, 0, len(prog.Labels))
+	for label := range prog.Labels {
+		if _, isIO := ioLabels[label]; !isIO && !strings.HasPrefix(label, ".") {
+			symbols = append(symbols, label)
+		}
+	}
+	slices.Sort(symbols)
+	for _, label := range symbols {
+		fmt.Fprintf(&builder, "#symbol %s %d\n", label, prog.Labels[label])
+	}
+
+	fmt.Fprintf(&builder, "#checksum %08d\n", Checksum(words))
+	for _, word := range words {
+		fmt.Fprintf(&builder, "%04d\n", word)
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
@@ -12,9 +12,12 @@ type PrintOptions struct {
 	Address     bool
 	MachineCode bool
 	SourceCode  bool
+	Labels      bool // show labels on their own line even when showing addresses
 }
 
 type Program struct {
+	Name         string // optional name given in header of machine code files
+	Entry        uint   // address where execution starts
 	Labels       SymbolTable
 	Instructions []Instruction
 }
@@ -10,6 +10,10 @@ func (inst *IncInstruction) AssignRegisters() {
 	if inst.err != nil {
 		return
 	}
+	if len(inst.parsedRegIndicies) == 0 {
+		inst.err = fmt.Errorf("INC needs a register operand")
+		return
+	}
 	inst.regIndicies[Rd] = inst.parsedRegIndicies[0]
 	inst.constant = 1
 }
@@ -22,6 +26,10 @@ func (inst *DecInstruction) AssignRegisters() {
 	if inst.err != nil {
 		return
 	}
+	if len(inst.parsedRegIndicies) == 0 {
+		inst.err = fmt.Errorf("DEC needs a register operand")
+		return
+	}
 	regIndex := inst.parsedRegIndicies[0]
 	inst.regIndicies[Rd] = regIndex
 	inst.constant = -1
@@ -35,6 +43,10 @@ func (inst *SubImmediateInstruction) AssignRegisters() {
 	if inst.err != nil {
 		return
 	}
+	if len(inst.parsedRegIndicies) == 0 {
+		inst.err = fmt.Errorf("SUBI needs a register operand")
+		return
+	}
 	inst.regIndicies[Rd] = inst.parsedRegIndicies[0]
 	inst.constant = -inst.constant
 }
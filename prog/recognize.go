@@ -0,0 +1,98 @@
+package prog
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// A decoded instruction shown as the pseudo instruction a programmer would
+// have written for it, such as INC x1 rather than ADDI x1, 1. Everything except
+// how the instruction is shown is handled by the decoded instruction.
+type PseudoInstruction struct {
+	Instruction
+	pseudoCode  Opcode
+	regs        []uint // register operands shown
+	hasConstant bool   // whether constant or label is shown after registers
+}
+
+// Pseudo instruction equivalent to inst, which should have been decoded from
+// machine code. Returns inst itself when no pseudo instruction applies
+func RecognizePseudo(inst Instruction) Instruction {
+	code := inst.MachineCode()
+	d, a, b := code/100%10, code/10%10, code%10
+	k := inst.Constant()
+
+	pseudo := func(opcode Opcode, hasConstant bool, regs ...uint) Instruction {
+		return &PseudoInstruction{
+			Instruction: inst,
+			pseudoCode:  opcode,
+			regs:        regs,
+			hasConstant: hasConstant,
+		}
+	}
+
+	switch inst.PseudoCode() {
+	case ADD:
+		switch {
+		case d == 0 && a == 0 && b == 0:
+			return pseudo(NOP, false)
+		case a == 0 && b == 0:
+			return pseudo(CLR, false, d)
+		case b == 0:
+			return pseudo(MOVE, false, d, a)
+		case a == 0:
+			return pseudo(MOVE, false, d, b)
+		}
+	case ADDI:
+		switch k {
+		case 1:
+			return pseudo(INC, false, d)
+		case -1:
+			return pseudo(DEC, false, d)
+		}
+	case LOAD:
+		if a == 0 && k == -1 {
+			return pseudo(INP, false, d)
+		}
+	case STOR:
+		if a == 0 && k == -1 {
+			return pseudo(OUT, false, d)
+		}
+	case JMP:
+		// JMP x9 without a constant is a return rather than a call
+		if d == 9 && k != 0 {
+			return pseudo(CALL, true)
+		}
+	case BEQ:
+		if d == 0 && a == 0 && k != 0 {
+			return pseudo(BRA, true)
+		}
+	}
+	return inst
+}
+
+func (inst *PseudoInstruction) PseudoCode() Opcode {
+	return inst.pseudoCode
+}
+
+func (inst *PseudoInstruction) SourceCode() string {
+	var buffer bytes.Buffer
+	printMnemonic(&buffer, inst.pseudoCode)
+	printRegisterOperands(&buffer, inst.regs)
+
+	if inst.hasConstant {
+		if len(inst.regs) > 0 {
+			fmt.Fprintf(&buffer, ", ")
+		}
+		if label := inst.Label(); label != "" {
+			LabelColor.Fprintf(&buffer, "%s", label)
+		} else {
+			NumberColor.Fprintf(&buffer, "%d", inst.Constant())
+		}
+	}
+	return buffer.String()
+}
+
+func (inst *PseudoInstruction) String() string {
+	return inst.SourceCode()
+}
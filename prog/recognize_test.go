@@ -0,0 +1,39 @@
+package prog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func testRecognize(t *testing.T, machinecode uint, expect string) {
+	inst := NewInstruction(Opcode(machinecode / 1000))
+	inst.DecodeOperands(machinecode % 1000)
+
+	got := strings.TrimSpace(RecognizePseudo(inst).SourceCode())
+	if got != expect {
+		t.Errorf("expect '%s' from decoding %04d but got '%s'", expect, machinecode, got)
+	}
+}
+
+func TestRecognizePseudo(t *testing.T) {
+	color.NoColor = true
+
+	testRecognize(t, 1320, "MOVE x3, x2")
+	testRecognize(t, 1302, "MOVE x3, x2")
+	testRecognize(t, 1300, "CLR  x3")
+	testRecognize(t, 1000, "NOP")
+	testRecognize(t, 2301, "INC  x3")
+	testRecognize(t, 2399, "DEC  x3")
+	testRecognize(t, 5109, "INP  x1")
+	testRecognize(t, 7309, "OUT  x3")
+	testRecognize(t, 8942, "CALL 42")
+	testRecognize(t, 3, "BRA  3")
+
+	// not pseudo instructions
+	testRecognize(t, 1312, "ADD  x3, x1, x2")
+	testRecognize(t, 8900, "JMP  x9, 0")
+	testRecognize(t, 5119, "LOAD x1, x1, -1")
+	testRecognize(t, 0, "HLT")
+}
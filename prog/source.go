@@ -0,0 +1,40 @@
+package prog
+
+import "strings"
+
+// Split a line of assembly source code into an optional label, the code itself
+// and a trailing comment. The returned comment includes the leading "//" while
+// label excludes the trailing ':'. All parts are trimmed of surrounding whitespace.
+//
+//	"loop: ADD x1, x2 // sum"  ->  "loop", "ADD x1, x2", "// sum"
+func SplitLine(line string) (label, code, comment string) {
+	code = strings.TrimSpace(line)
+
+	if i := commentStart(code); i >= 0 {
+		comment = strings.TrimSpace(code[i:])
+		code = strings.TrimSpace(code[:i])
+	}
+
+	// a colon inside a string literal is not a label separator
+	quote := strings.IndexRune(code, '"')
+	if i := strings.IndexRune(code, ':'); i >= 0 && (quote < 0 || i < quote) {
+		label = strings.TrimSpace(code[:i])
+		code = strings.TrimSpace(code[i+1:])
+	}
+	return
+}
+
+// Locate start of a "//" comment which is not inside a string literal.
+// Returns -1 if line has no comment
+func commentStart(line string) int {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '"':
+			inString = !inString
+		case !inString && strings.HasPrefix(line[i:], "//"):
+			return i
+		}
+	}
+	return -1
+}
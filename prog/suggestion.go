@@ -0,0 +1,163 @@
+package prog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A machine-applicable fix for an error in a line of assembly code. The code
+// on the offending line, excluding any label and comment, should be replaced
+// with Lines.
+type Suggestion struct {
+	Message string   // describes what the fix does
+	Lines   []string // instructions replacing the erroneous one
+}
+
+// An error which carries suggested fixes for it
+type FixableError struct {
+	Err         error
+	Suggestions []Suggestion
+}
+
+func (e *FixableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FixableError) Unwrap() error {
+	return e.Err
+}
+
+// Split an out of range immediate value into an instruction loading the first part
+// followed by ADDI instructions adding the rest. E.g. LODI x1, 90 becomes
+//
+//	LODI x1, 49
+//	ADDI x1, 41
+func splitImmediate(opcode Opcode, reg uint, value int) (Suggestion, bool) {
+	// beyond this, storing the value with DAT and using LOAD is a better option
+	if value > 4*49 || value < -4*50 {
+		return Suggestion{}, false
+	}
+
+	lines := make([]string, 0, 2)
+	for first := true; first || value != 0; first = false {
+		part := value
+		if part > 49 {
+			part = 49
+		} else if part < -50 {
+			part = -50
+		}
+		value -= part
+
+		mnemonic := ADDI
+		if first {
+			mnemonic = opcode
+		}
+		lines = append(lines, fmt.Sprintf("%-5vx%d, %d", mnemonic, reg, part))
+	}
+
+	return Suggestion{
+		Message: fmt.Sprintf("split constant into %d instructions", len(lines)),
+		Lines:   lines,
+	}, true
+}
+
+// Replace a conditional branch to a label too far away with an inverted branch
+// skipping over an absolute JMP to the label. Labels starting with a dot hold
+// an offset from a base address rather than an address, so JMP cannot be used
+func longBranch(opcode Opcode, regs []uint, label string) (Suggestion, bool) {
+	if strings.HasPrefix(label, ".") {
+		return Suggestion{}, false
+	}
+	jump := fmt.Sprintf("%-5v%s", JMP, label)
+	var lines []string
+
+	switch {
+	case opcode == BRA:
+		lines = []string{jump}
+	case len(regs) != 2:
+		return Suggestion{}, false
+	case opcode == BEQ:
+		// skip JMP when a != b
+		a, b := regs[0], regs[1]
+		lines = []string{
+			fmt.Sprintf("%-5vx%d, x%d, 3", BGT, a, b),
+			fmt.Sprintf("%-5vx%d, x%d, 2", BGT, b, a),
+			jump,
+		}
+	case opcode == BGT || opcode == BLT:
+		// skip JMP when a <= b for BGT and when a >= b for BLT
+		a, b := regs[0], regs[1]
+		if opcode == BLT {
+			a, b = b, a
+		}
+		lines = []string{
+			fmt.Sprintf("%-5vx%d, x%d, 3", BGT, b, a),
+			fmt.Sprintf("%-5vx%d, x%d, 2", BEQ, a, b),
+			jump,
+		}
+	default:
+		return Suggestion{}, false
+	}
+
+	message := fmt.Sprintf("replace %v with inverted branch and JMP to %s", opcode, label)
+	if opcode == BRA {
+		message = fmt.Sprintf("replace %v with JMP to %s", opcode, label)
+	}
+	return Suggestion{Message: message, Lines: lines}, true
+}
+
+// Suggest the mnemonic closest to a misspelled one
+func SuggestMnemonic(mnemonic string, operands []string) (Suggestion, bool) {
+	best, bestDistance := "", 3 // don't suggest anything too different
+	for _, opstr := range AllOpcodeStrings {
+		distance := editDistance(strings.ToUpper(mnemonic), opstr)
+		if distance < bestDistance {
+			best, bestDistance = opstr, distance
+		}
+	}
+	if best == "" {
+		return Suggestion{}, false
+	}
+
+	line := best
+	if len(operands) > 0 {
+		line = fmt.Sprintf("%-5s%s", best, strings.Join(operands, ", "))
+	}
+	return Suggestion{
+		Message: fmt.Sprintf("replace '%s' with %s", mnemonic, best),
+		Lines:   []string{line},
+	}, true
+}
+
+// Edit distance between a and b, where insertion, deletion, substitution and
+// transposition of two adjacent characters all count as one edit
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = d[i-1][j-1] + cost
+			if d[i-1][j]+1 < d[i][j] {
+				d[i][j] = d[i-1][j] + 1
+			}
+			if d[i][j-1]+1 < d[i][j] {
+				d[i][j] = d[i][j-1] + 1
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
@@ -27,27 +27,21 @@ func ReadSymTable(reader io.Reader) SymbolTable {
 	address := 0
 	baseAddress := 0
 	for scanner.Scan() {
-		line := strings.Trim(scanner.Text(), " \t")
-		n := len(line)
-
-		if n == 0 || strings.HasPrefix(line, "//") {
-			continue
-		}
-
-		if i := strings.IndexRune(line, ':'); i >= 0 {
+		label, code, _ := SplitLine(scanner.Text())
 
+		if label != "" {
 			// check if we should record an offset or absolute address
-			if strings.HasPrefix(line, ".") {
-				labels[line[0:i]] = uint(address - baseAddress)
+			if strings.HasPrefix(label, ".") {
+				labels[label] = uint(address - baseAddress)
 			} else {
-				labels[line[0:i]] = uint(address)
+				labels[label] = uint(address)
 				baseAddress = address
 			}
+		}
 
-			// is there anything beyond the label?
-			if n == i+1 {
-				continue
-			}
+		// is there anything beyond the label?
+		if code == "" {
+			continue
 		}
 		address++
 	}
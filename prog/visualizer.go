@@ -40,9 +40,14 @@ func (ctx *PrintContext) Print(writer io.Writer, input <-chan AddressInstruction
 		addr := addrInst.Addr
 		inst := addrInst.Inst
 
+		label, hasLabel := addrToLabel[uint(addr)]
 		if options.Address {
+			if hasLabel && options.Labels && options.SourceCode {
+				LabelColor.Fprint(writer, label, ":")
+				fmt.Fprintln(writer)
+			}
 			AddressColor.Fprintf(writer, "%02d ", addr)
-		} else if label, ok := addrToLabel[uint(addr)]; ok && options.SourceCode {
+		} else if hasLabel && options.SourceCode {
 			LabelColor.Fprint(writer, label, ":")
 			fmt.Fprintln(writer)
 		}
@@ -0,0 +1,15 @@
+package sim
+
+// DataCache sits between instructions and memory. It is told about every
+// word of memory read or written by an instruction, at the program counter
+// pc, and gives the cycles the access had to wait for memory. These are
+// added to the cycles given by the cost model
+type DataCache interface {
+	Access(pc, address uint, write bool) uint
+}
+
+func (comp *Computer) accessCache(address uint, write bool) {
+	if comp.executing && comp.Cache != nil {
+		comp.stalls += comp.Cache.Access(comp.pc, address, write)
+	}
+}
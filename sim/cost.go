@@ -0,0 +1,99 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ordovician/calcutron/prog"
+)
+
+// CostModel decides how many clock cycles each instruction takes. Counting
+// cycles rather than instructions shows the cost of going to memory instead
+// of keeping values in registers. Saved as JSON it looks like:
+//
+//	{
+//	  "default": 1,
+//	  "taken_branch": 1,
+//	  "opcodes": {"LOAD": 3, "STOR": 3, "INP": 5}
+//	}
+//
+// Opcodes may be base or pseudo instructions, with a pseudo instruction such
+// as INP taking precedence over its base instruction LOAD. Instructions not
+// listed take the default number of cycles. Branches and jumps which change
+// the program counter take taken_branch extra cycles.
+type CostModel struct {
+	Default     uint            `json:"default"`
+	TakenBranch uint            `json:"taken_branch"`
+	Opcodes     map[string]uint `json:"opcodes"`
+}
+
+// Cost model used unless another is given, where accessing memory is three
+// times as slow as working with registers
+var DefaultCostModel = CostModel{
+	Default:     1,
+	TakenBranch: 1,
+	Opcodes:     map[string]uint{"LOAD": 3, "STOR": 3},
+}
+
+// Cycles taken by executing inst, where taken tells whether it changed the
+// program counter by branching or jumping
+func (model *CostModel) Cost(inst prog.Instruction, taken bool) uint {
+	cycles, ok := model.Opcodes[prog.RecognizePseudo(inst).PseudoCode().String()]
+	if !ok {
+		cycles, ok = model.Opcodes[inst.Opcode().String()]
+	}
+	if !ok {
+		cycles = model.Default
+	}
+	if taken {
+		cycles += model.TakenBranch
+	}
+	return cycles
+}
+
+// Read cost model written as JSON. Default is 1 when not given
+func ReadCostModel(reader io.Reader) (*CostModel, error) {
+	model := CostModel{Default: 1}
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&model); err != nil {
+		return nil, fmt.Errorf("unable to read cost model because %w", err)
+	}
+
+	opcodes := make(map[string]uint)
+	for mnemonic, cycles := range model.Opcodes {
+		opcode, ok := prog.ParseOpcode(mnemonic)
+		if !ok || opcode == prog.DAT {
+			return nil, fmt.Errorf("cost model gives cycles for unknown instruction %s", mnemonic)
+		}
+		opcodes[strings.ToUpper(mnemonic)] = cycles
+	}
+	model.Opcodes = opcodes
+	return &model, nil
+}
+
+// Load cost model from JSON file at filepath
+func LoadCostModelFile(filepath string) (*CostModel, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("could not load cost model: %w", err)
+	}
+	defer file.Close()
+
+	return ReadCostModel(file)
+}
+
+// Number of clock cycles taken by instructions executed since last reset
+func (comp *Computer) Cycles() uint {
+	return comp.cycles
+}
+
+func (comp *Computer) costModel() *CostModel {
+	if comp.Costs != nil {
+		return comp.Costs
+	}
+	return &DefaultCostModel
+}
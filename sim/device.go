@@ -0,0 +1,134 @@
+package sim
+
+import (
+	"fmt"
+
+	"github.com/ordovician/calcutron/prog"
+)
+
+// Address of the tape which LOAD reads inputs from and STOR writes outputs
+// to, written as -1 in assembly code
+const TapeAddress = MemorySize - 1
+
+// Device is a peripheral mapped into memory. It claims Size consecutive
+// addresses, and LOAD and STOR instructions accessing any of them talk to the
+// device instead of memory. Offsets are counted from the first address
+// claimed. Reading returns false when the device cannot give a value, such as
+// when the tape runs out of inputs, which stops the program
+type Device interface {
+	Name() string
+	Size() uint
+	Read(offset uint) (value uint, ok bool)
+	Write(offset uint, value uint)
+}
+
+// Devices which also implement Resetter are reset with the computer, so a
+// program can be run again and give the same result
+type Resetter interface {
+	Reset()
+}
+
+// Devices which also implement Undoer keep their own state, and can have it
+// saved before an instruction reads, writes or ticks them and put back when
+// the instruction is undone, so stepping back and running forward again does
+// the same as the first time
+type Undoer interface {
+	SaveState() any
+	RestoreState(state any)
+}
+
+// Device attached to computer at Address
+type MappedDevice struct {
+	Address uint
+	Device  Device
+}
+
+// Whether address is claimed by device
+func (mapped *MappedDevice) Claims(address uint) bool {
+	return address >= mapped.Address && address < mapped.Address+mapped.Device.Size()
+}
+
+// Input and output tape, which is attached to every computer at TapeAddress
+type tape struct {
+	comp *Computer
+}
+
+func (t *tape) Name() string {
+	return "tape"
+}
+
+func (t *tape) Size() uint {
+	return 1
+}
+
+func (t *tape) Read(offset uint) (uint, bool) {
+	value, ok := t.comp.PopInput()
+	return prog.Complement(value, 1e4), ok
+}
+
+func (t *tape) Write(offset uint, value uint) {
+	t.comp.PushOutput(prog.Signed(value, 1e4))
+}
+
+// Devices attached to computer, starting with the tape
+func (comp *Computer) Devices() []MappedDevice {
+	if comp.devices == nil {
+		comp.devices = []MappedDevice{{Address: TapeAddress, Device: &tape{comp}}}
+	}
+	return comp.devices
+}
+
+// Attach device to computer so it claims the addresses from address and up.
+// Fails if any of them are outside of memory or claimed by another device
+func (comp *Computer) AttachDevice(address uint, device Device) error {
+	size := device.Size()
+	if size == 0 || address+size > MemorySize {
+		return fmt.Errorf("%s device of %d words does not fit in memory at address %d", device.Name(), size, address)
+	}
+	added := MappedDevice{Address: address, Device: device}
+	for _, other := range comp.Devices() {
+		if added.Claims(other.Address) || other.Claims(address) {
+			return fmt.Errorf("%s device at address %d overlaps %s device at address %d",
+				device.Name(), address, other.Device.Name(), other.Address)
+		}
+	}
+	comp.devices = append(comp.devices, added)
+	return nil
+}
+
+// Device claiming address, if any
+func (comp *Computer) DeviceAt(address uint) (*MappedDevice, bool) {
+	devices := comp.Devices()
+	for i := range devices {
+		if devices[i].Claims(address) {
+			return &devices[i], true
+		}
+	}
+	return nil, false
+}
+
+// Device claiming address while executing an instruction
+func (comp *Computer) deviceAt(address uint) (*MappedDevice, bool) {
+	if !comp.executing {
+		return nil, false
+	}
+	return comp.DeviceAt(address)
+}
+
+func (comp *Computer) resetDevices() {
+	for _, mapped := range comp.devices {
+		if resetter, ok := mapped.Device.(Resetter); ok {
+			resetter.Reset()
+		}
+	}
+}
+
+// Execute inst, returning false if it halted or was stopped by a device. An
+// instruction stopped by a device changes no registers or memory after that
+func (comp *Computer) Execute(inst prog.Instruction) bool {
+	comp.stopped = false
+	comp.executing = true
+	running := inst.Run(comp)
+	comp.executing = false
+	return running && !comp.stopped
+}
@@ -0,0 +1,164 @@
+package sim
+
+import (
+	"golang.org/x/exp/slices"
+)
+
+// Number of executed instructions the debugger can undo unless told otherwise
+const DefaultHistorySize = 10000
+
+// Old value of a register or memory word changed by an instruction
+type change struct {
+	index uint // register or memory address
+	value uint
+}
+
+// State of device before an instruction used it
+type deviceState struct {
+	device Undoer
+	state  any
+}
+
+// Everything needed to undo executing a single instruction
+type undoEntry struct {
+	pc        uint
+	inpos     int
+	outputs   int  // number of outputs before instruction
+	cycles    uint // cycles taken before instruction
+	counted   bool // instruction completed and was added to instCount
+	interrupt interruptState
+	registers []change
+	memory    []change
+	devices   []deviceState
+}
+
+// Start recording changes made by instruction about to be executed
+func (comp *Computer) beginUndo() {
+	if comp.HistorySize <= 0 {
+		return
+	}
+	comp.recording = &undoEntry{
+		pc:        comp.pc,
+		inpos:     comp.inpos,
+		outputs:   len(comp.outputs),
+		cycles:    comp.cycles,
+		interrupt: comp.interrupts,
+	}
+}
+
+// Stop recording changes. Every completed instruction is kept, even a JMP to
+// itself, so that StepBack undoes the same steps counted by instCount. An
+// instruction which stopped the program is only kept if it did anything.
+// History is trimmed to HistorySize, but only once it has grown to twice the
+// size to avoid copying it on every instruction
+func (comp *Computer) endUndo(completed bool) {
+	entry := comp.recording
+	comp.recording = nil
+	if entry == nil {
+		return
+	}
+	entry.counted = completed
+
+	unchanged := entry.pc == comp.pc && entry.inpos == comp.inpos && entry.outputs == len(comp.outputs) &&
+		entry.interrupt == comp.interrupts
+	if !completed && unchanged && len(entry.registers) == 0 && len(entry.memory) == 0 && len(entry.devices) == 0 {
+		return
+	}
+
+	comp.history = append(comp.history, *entry)
+	if n := len(comp.history); n >= 2*comp.HistorySize {
+		comp.history = slices.Clone(comp.history[n-comp.HistorySize:])
+	}
+}
+
+// Save state of device before the instruction being recorded first uses it
+func (comp *Computer) recordDevice(device Device) {
+	undoer, ok := device.(Undoer)
+	if !ok || comp.recording == nil {
+		return
+	}
+	for _, saved := range comp.recording.devices {
+		if saved.device == undoer {
+			return
+		}
+	}
+	comp.recording.devices = append(comp.recording.devices, deviceState{undoer, undoer.SaveState()})
+}
+
+// Number of executed instructions which can be undone with StepBack
+func (comp *Computer) HistoryLen() int {
+	if len(comp.history) > comp.HistorySize {
+		return comp.HistorySize
+	}
+	return len(comp.history)
+}
+
+// Forget all executed instructions, so they can no longer be undone
+func (comp *Computer) ClearHistory() {
+	comp.history = nil
+}
+
+// Undo last executed instruction, putting computer back into the state it was
+// in before executing it. Returns false if there is nothing to undo
+func (comp *Computer) StepBack() bool {
+	if comp.HistoryLen() == 0 {
+		return false
+	}
+	n := len(comp.history)
+	entry := comp.history[n-1]
+	comp.history = comp.history[:n-1]
+
+	// undo in reverse order in case the same register or address changed twice
+	for i := len(entry.registers) - 1; i >= 0; i-- {
+		comp.registers[entry.registers[i].index] = entry.registers[i].value
+	}
+	for i := len(entry.memory) - 1; i >= 0; i-- {
+		comp.memory[entry.memory[i].index] = entry.memory[i].value
+	}
+	for _, saved := range entry.devices {
+		saved.device.RestoreState(saved.state)
+	}
+	comp.pc = entry.pc
+	comp.inpos = entry.inpos
+	comp.outputs = comp.outputs[:entry.outputs]
+	comp.cycles = entry.cycles
+	comp.interrupts = entry.interrupt
+	if entry.counted && comp.instCount > 0 {
+		comp.instCount--
+	}
+	return true
+}
+
+// Step back until reaching a breakpoint or the start of history. Returns number of steps undone
+func (comp *Computer) ReverseContinue() int {
+	steps := 0
+	for comp.StepBack() {
+		steps++
+		if comp.breakpoints[comp.pc] {
+			break
+		}
+	}
+	return steps
+}
+
+// Stop running program before executing instruction at addr
+func (comp *Computer) SetBreakpoint(addr uint) {
+	if comp.breakpoints == nil {
+		comp.breakpoints = make(map[uint]bool)
+	}
+	comp.breakpoints[addr] = true
+}
+
+func (comp *Computer) ClearBreakpoint(addr uint) {
+	delete(comp.breakpoints, addr)
+}
+
+// Addresses of breakpoints in ascending order
+func (comp *Computer) Breakpoints() []uint {
+	addresses := make([]uint, 0, len(comp.breakpoints))
+	for addr := range comp.breakpoints {
+		addresses = append(addresses, addr)
+	}
+	slices.Sort(addresses)
+	return addresses
+}
@@ -0,0 +1,184 @@
+package sim
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ordovician/calcutron/prog"
+)
+
+// Register the program counter is saved in when an interrupt is taken, so
+// that the handler can return to the interrupted program with JMP x8
+const InterruptRegister = 8
+
+// State of interrupts, which is part of the processor rather than any device
+type interruptState struct {
+	enabled  bool
+	enableIn int    // instructions left before interrupts are enabled, 0 if not enabling
+	vector   uint   // address of interrupt handler
+	cause    uint   // number of interrupt last taken
+	pending  uint64 // bit n-1 set when interrupt n has been raised but not taken
+}
+
+// Devices which also implement Ticker are told whenever an instruction has
+// been executed, such as a timer counting instructions
+type Ticker interface {
+	Tick()
+}
+
+// Give source of interrupts a number, which the interrupt handler can read
+// from the interrupt controller to find out what caused an interrupt
+func (comp *Computer) AddInterruptSource(name string) uint {
+	comp.interruptSources = append(comp.interruptSources, name)
+	return uint(len(comp.interruptSources))
+}
+
+// Raise interrupt number n. It is taken after the current instruction, or
+// once interrupts are enabled
+func (comp *Computer) RaiseInterrupt(n uint) {
+	if n > 0 && n <= uint(len(comp.interruptSources)) {
+		comp.interrupts.pending |= 1 << (n - 1)
+	}
+}
+
+func (comp *Computer) InterruptsEnabled() bool {
+	return comp.interrupts.enabled
+}
+
+// Address of interrupt handler
+func (comp *Computer) InterruptVector() uint {
+	return comp.interrupts.vector
+}
+
+// Names of sources of interrupts raised but not yet taken
+func (comp *Computer) PendingInterrupts() []string {
+	var names []string
+	for i, name := range comp.interruptSources {
+		if comp.interrupts.pending&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// After executing an instruction, tick devices and jump to the interrupt
+// handler if an interrupt is pending and interrupts are enabled. Interrupts
+// are disabled while the handler runs, and enabling them takes effect only
+// after the next instruction, so the handler can enable them just before
+// returning without being interrupted before it gets back
+func (comp *Computer) handleInterrupts() {
+	for _, mapped := range comp.devices {
+		if ticker, ok := mapped.Device.(Ticker); ok {
+			comp.recordDevice(mapped.Device)
+			ticker.Tick()
+		}
+	}
+
+	state := &comp.interrupts
+	if state.enableIn > 0 {
+		state.enableIn--
+		state.enabled = state.enableIn == 0
+	}
+	if !state.enabled || state.pending == 0 {
+		return
+	}
+
+	n := uint(1)
+	for state.pending&(1<<(n-1)) == 0 {
+		n++
+	}
+	state.pending &^= 1 << (n - 1)
+	state.cause = n
+	state.enabled = false
+	comp.SetRegister(InterruptRegister, int(comp.pc))
+	comp.pc = state.vector
+}
+
+// InterruptController lets programs enable and disable interrupts and set
+// the address of their handler. It claims three words:
+//
+//	0  enable: write 1 to enable and 0 to disable interrupts
+//	1  vector: address of interrupt handler
+//	2  cause: number of interrupt being handled
+type InterruptController struct {
+	comp *Computer
+}
+
+func NewInterruptController(comp *Computer) *InterruptController {
+	return &InterruptController{comp}
+}
+
+func (ctrl *InterruptController) Name() string {
+	return "interrupts"
+}
+
+func (ctrl *InterruptController) Size() uint {
+	return 3
+}
+
+func (ctrl *InterruptController) Read(offset uint) (uint, bool) {
+	state := &ctrl.comp.interrupts
+	switch offset {
+	case 0:
+		if state.enabled || state.enableIn > 0 {
+			return 1, true
+		}
+		return 0, true
+	case 1:
+		return state.vector, true
+	default:
+		return state.cause, true
+	}
+}
+
+func (ctrl *InterruptController) Write(offset uint, value uint) {
+	state := &ctrl.comp.interrupts
+	switch offset {
+	case 0:
+		if value == 0 {
+			state.enabled = false
+			state.enableIn = 0
+		} else if !state.enabled {
+			// takes effect after the instruction following this one
+			state.enableIn = 2
+		}
+	case 1:
+		state.vector = value
+	}
+}
+
+// Print whether interrupts are enabled and which are pending, if the program
+// is able to use interrupts
+func (comp *Computer) PrintInterrupts(writer io.Writer) {
+	if len(comp.interruptSources) == 0 && !comp.hasDevice("interrupts") {
+		return
+	}
+
+	state := &comp.interrupts
+	fmt.Fprint(writer, "Interrupts: ")
+	if state.enabled {
+		fmt.Fprint(writer, "enabled   ")
+	} else if state.enableIn > 0 {
+		fmt.Fprint(writer, "enabling  ")
+	} else {
+		fmt.Fprint(writer, "disabled  ")
+	}
+	fmt.Fprint(writer, "Vector: ")
+	prog.NumberColor.Fprintf(writer, "%02d   ", state.vector)
+	fmt.Fprint(writer, "Pending: ")
+	if pending := comp.PendingInterrupts(); len(pending) > 0 {
+		prog.NumberColor.Fprintln(writer, strings.Join(pending, ", "))
+	} else {
+		fmt.Fprintln(writer, "none")
+	}
+}
+
+func (comp *Computer) hasDevice(name string) bool {
+	for _, mapped := range comp.devices {
+		if mapped.Device.Name() == name {
+			return true
+		}
+	}
+	return false
+}
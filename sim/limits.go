@@ -0,0 +1,109 @@
+package sim
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"time"
+
+	"github.com/ordovician/calcutron/prog"
+)
+
+// Limits on how long a program is allowed to run
+type Limits struct {
+	MaxSteps    int           // instructions executed before stopping, 0 for no limit
+	Timeout     time.Duration // time spent running before stopping, 0 for no limit
+	DetectLoops bool          // stop when the computer gets back to a state it has been in before
+}
+
+// Limits used by cutron run and the debugger unless told otherwise
+var DefaultLimits = Limits{MaxSteps: 5000, DetectLoops: true}
+
+// Everything deciding what a program will do next. Memory is hashed to keep
+// states small, since we store one for every backward jump. Devices may give
+// different values every time they are read, so a program which has read a
+// device since getting to a state is never considered to be in a loop
+type machineState struct {
+	pc        uint
+	registers [10]uint
+	inpos     int
+	ioReads   uint
+	clock     uint // steps taken, when a device counts them
+	interrupt interruptState
+	memory    uint64
+}
+
+// Steps taken if any device counts them, since that device may do something
+// different the next time the program gets to the same state
+func (comp *Computer) clock() uint {
+	for _, mapped := range comp.devices {
+		if _, ok := mapped.Device.(Ticker); ok {
+			return comp.instCount
+		}
+	}
+	return 0
+}
+
+func (comp *Computer) state() machineState {
+	bytes := make([]byte, 2*len(comp.memory))
+	for i, word := range comp.memory {
+		binary.LittleEndian.PutUint16(bytes[2*i:], uint16(word))
+	}
+	hash := fnv.New64a()
+	hash.Write(bytes)
+
+	return machineState{
+		pc:        comp.pc,
+		registers: comp.registers,
+		inpos:     comp.inpos,
+		ioReads:   comp.ioReads,
+		clock:     comp.clock(),
+		interrupt: comp.interrupts,
+		memory:    hash.Sum64(),
+	}
+}
+
+// Run program until it stops, reaches a breakpoint or exceeds limits. The
+// instruction at PC is always executed, so that running again continues past
+// a breakpoint. Executed instructions are sent to out unless it is nil.
+//
+// A program which jumps back to a state it has been in before will repeat
+// the same instructions forever, as it has no way of doing anything
+// different the next time around. With loop detection on, states are
+// recorded at every backward jump and execution stops with InfiniteLoop when
+// one repeats.
+func (comp *Computer) RunLimited(limits Limits, out chan<- prog.AddressInstruction) Result {
+	start := time.Now()
+	visited := make(map[machineState]uint)
+
+	for i := 0; limits.MaxSteps <= 0 || i < limits.MaxSteps; i++ {
+		var result Result
+		if out != nil {
+			result = comp.StepChannel(out)
+		} else {
+			result = comp.Step()
+		}
+		if result.Reason != Running {
+			return result
+		}
+
+		if comp.breakpoints[comp.pc] {
+			return Result{Reason: Breakpoint, PC: comp.pc, Instruction: comp.Instruction()}
+		}
+
+		if limits.Timeout > 0 && time.Since(start) > limits.Timeout {
+			return comp.stop(Timeout)
+		}
+
+		if limits.DetectLoops && comp.pc <= result.PC {
+			state := comp.state()
+			if steps, ok := visited[state]; ok {
+				result := comp.stop(InfiniteLoop)
+				result.LoopSteps = comp.instCount - steps
+				comp.Err = result.Err()
+				return result
+			}
+			visited[state] = comp.instCount
+		}
+	}
+	return comp.stop(StepLimit)
+}
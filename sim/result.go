@@ -0,0 +1,100 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ordovician/calcutron/prog"
+)
+
+var ErrAllInputRead = errors.New("all inputs read")
+var ErrProgramHalt = errors.New("reached halt instruction")
+var ErrBreakpoint = errors.New("stopped at breakpoint")
+var ErrStepLimit = errors.New("step limit reached")
+var ErrTimeout = errors.New("time limit reached")
+var ErrInfiniteLoop = errors.New("infinite loop detected")
+var ErrPCOutOfRange = errors.New("program counter outside of memory")
+var ErrExecuteData = errors.New("data is not an instruction")
+
+// Reason why computer stopped executing instructions
+//
+// There is no memory fault. Memory has 10000 words, addressed 0 to 9999, and
+// LOAD and STOR compute their address modulo 10000, so any address an
+// instruction can form wraps around to a word in memory. Only the program
+// counter can get outside of memory, which is PCOutOfRange
+type HaltReason int
+
+const (
+	Running        HaltReason = iota // executed instruction and can continue
+	Halted                           // reached HLT instruction
+	InputExhausted                   // tried to read input when there was none left
+	Breakpoint                       // about to execute instruction with a breakpoint
+	StepLimit                        // executed maximum number of instructions allowed
+	Timeout                          // ran for longer than time allowed
+	InfiniteLoop                     // got back to an earlier state, and will repeat forever
+	PCOutOfRange                     // program counter points outside of memory
+	ExecutedData                     // program counter points to a DAT directive
+)
+
+var haltReasonErrors = map[HaltReason]error{
+	Halted:         ErrProgramHalt,
+	InputExhausted: ErrAllInputRead,
+	Breakpoint:     ErrBreakpoint,
+	StepLimit:      ErrStepLimit,
+	Timeout:        ErrTimeout,
+	InfiniteLoop:   ErrInfiniteLoop,
+	PCOutOfRange:   ErrPCOutOfRange,
+	ExecutedData:   ErrExecuteData,
+}
+
+func (reason HaltReason) String() string {
+	if reason == Running {
+		return "running"
+	}
+	return haltReasonErrors[reason].Error()
+}
+
+// Result of executing one or more instructions
+type Result struct {
+	Reason      HaltReason
+	PC          uint             // address of last instruction executed, or attempted executed
+	Instruction prog.Instruction // instruction at PC, nil when PC is outside memory
+	LoopSteps   uint             // steps taken by each round of an infinite loop
+}
+
+// Whether program stopped because of an error in the program, rather than
+// halting or reading all its input as programs normally do
+func (result Result) Fault() bool {
+	return result.Reason >= StepLimit
+}
+
+// Error describing why program stopped, or nil if it is still running.
+// Use errors.Is with ErrProgramHalt, ErrAllInputRead etc to check the reason
+func (result Result) Err() error {
+	switch result.Reason {
+	case Running:
+		return nil
+	case InfiniteLoop:
+		return fmt.Errorf("%w at PC %02d, repeating every %d steps", ErrInfiniteLoop, result.PC, result.LoopSteps)
+	}
+	if result.Instruction == nil {
+		// jumping to a negative address wraps around to a huge one
+		return fmt.Errorf("%w at address %02d", haltReasonErrors[result.Reason], int(result.PC))
+	}
+
+	source := plainSource(result.Instruction)
+	return fmt.Errorf("%w at address %02d executing %s", haltReasonErrors[result.Reason], result.PC, source)
+}
+
+// Escape codes added by the color package
+var colorCodes = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// Source code of instruction without color codes or mnemonic padding, for
+// error messages and traces. Color codes are stripped rather than turned off,
+// since instructions may be printed in color at the same time
+func plainSource(inst prog.Instruction) string {
+	source := colorCodes.ReplaceAllString(prog.RecognizePseudo(inst).SourceCode(), "")
+	return strings.Join(strings.Fields(source), " ")
+}
@@ -3,7 +3,6 @@ package sim
 import (
 	"bufio"
 	"bytes"
-	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -18,24 +17,48 @@ import (
 	"github.com/ordovician/calcutron/utils"
 )
 
-var ErrAllInputRead = errors.New("all inputs read")
-var ErrProgramHalt = errors.New("reach halt instruction")
+// Number of words in memory of computer
+const MemorySize = 10000
 
 type Computer struct {
-	pc        uint             // Program counter 0-99
-	registers [10]uint         // CPU registers   0-9
-	memory    [9999]uint       // Computer memory 0-9999
-	inputs    []uint           // Input data to computer -5000-4999
-	outputs   []uint           // Output from computer   -5000-4999
-	inpos     int              // Current position input stream
-	instCount uint             // Count of number of instructions executed since last reset
-	labels    prog.SymbolTable // so we can lookup memory locations
-	Err       error            // last error
+	pc        uint              // Program counter 0-9999
+	registers [10]uint          // CPU registers   0-9
+	memory    [MemorySize]uint  // Computer memory 0-9999
+	data      map[uint]bool     // addresses of DAT directives, which must not be executed
+	image     *[MemorySize]uint // memory as it was when program was loaded
+	executing bool              // memory is accessed by an instruction rather than the debugger
+	stalls    uint              // cycles current instruction spent waiting for data cache
+	stopped   bool              // current instruction was stopped by a device unable to give a value
+	devices   []MappedDevice    // devices claiming memory addresses, starting with the tape
+	ioReads   uint              // number of reads from devices since last reset
+
+	interrupts       interruptState   // whether interrupts are enabled, pending etc
+	interruptSources []string         // names of devices raising each interrupt, from interrupt 1 and up
+	inputs           []uint           // Input data to computer -5000-4999
+	outputs          []uint           // Output from computer   -5000-4999
+	inpos            int              // Current position input stream
+	instCount        uint             // Count of number of instructions executed since last reset
+	cycles           uint             // clock cycles taken by instructions executed since last reset
+	entry            uint             // address where program starts
+	labels           prog.SymbolTable // so we can lookup memory locations
+	Limits           Limits           // limits used by debugger when running program, none if zero
+	Costs            *CostModel       // cycles taken by each instruction, DefaultCostModel if nil
+	Cache            DataCache        // cache between instructions and memory, if any
+	Err              error            // last error
+
+	HistorySize int           // number of executed instructions which can be undone, 0 for none
+	history     []undoEntry   // changes made by executed instructions, oldest first
+	recording   *undoEntry    // changes made by instruction being executed
+	breakpoints map[uint]bool // addresses where running program stops
+
+	Tracer  Tracer       // gets a record of every executed instruction, unless nil
+	tracing *TraceRecord // accesses made by instruction being executed
 }
 
 // valid registers are in range 0 to 9, but register 0 will always contains 0
 func (comp *Computer) Register(i uint) int {
 	if i > 0 && i <= 9 {
+		comp.traceRegisterRead(i)
 		return prog.Signed(comp.registers[i], 1e4)
 	} else {
 		return 0
@@ -44,8 +67,13 @@ func (comp *Computer) Register(i uint) int {
 
 // valid registers are in range 0 to 9, but register 0 will never get altered
 func (comp *Computer) SetRegister(i uint, value int) {
-	if i > 0 && i <= 9 {
+	if i > 0 && i <= 9 && !(comp.executing && comp.stopped) {
+		old := comp.registers[i]
+		if comp.recording != nil {
+			comp.recording.registers = append(comp.recording.registers, change{i, old})
+		}
 		comp.registers[i] = prog.Complement(value, 1e4)
+		comp.traceRegisterWrite(i, old)
 	}
 }
 
@@ -57,7 +85,26 @@ func (comp *Computer) SetPC(address uint) {
 	comp.pc = address
 }
 
+// Contents of memory at address, or 0 outside of memory. Instructions
+// cannot get outside, since their addresses wrap around. Addresses claimed
+// by a device are read from the device when executing an instruction, so
+// that the debugger can look at memory without reading input
 func (comp *Computer) Memory(address uint) uint {
+	if address >= MemorySize {
+		return 0
+	}
+	if mapped, ok := comp.deviceAt(address); ok {
+		comp.ioReads++
+		comp.recordDevice(mapped.Device)
+		value, ok := mapped.Device.Read(address - mapped.Address)
+		comp.stopped = comp.stopped || !ok
+		if ok {
+			comp.traceDeviceRead(mapped, address, value)
+		}
+		return value
+	}
+	comp.accessCache(address, false)
+	comp.traceMemoryRead(address)
 	return comp.memory[address]
 }
 
@@ -73,8 +120,28 @@ func (comp *Computer) ProgramSlice() []uint {
 	return comp.memory[:n+1]
 }
 
+// Writing outside of memory does nothing. Addresses claimed by a device are
+// written to the device when executing an instruction
 func (comp *Computer) SetMemory(address uint, value uint) {
+	if address >= MemorySize {
+		return
+	}
+	if comp.executing && comp.stopped {
+		return
+	}
+	if mapped, ok := comp.deviceAt(address); ok {
+		comp.recordDevice(mapped.Device)
+		mapped.Device.Write(address-mapped.Address, value)
+		comp.traceDeviceWrite(mapped, address, value)
+		return
+	}
+	comp.accessCache(address, true)
+	old := comp.memory[address]
+	if comp.recording != nil {
+		comp.recording.memory = append(comp.recording.memory, change{address, old})
+	}
 	comp.memory[address] = value
+	comp.traceMemoryWrite(address, old)
 }
 
 // will pop input set earlier or read input from Stdin if never set
@@ -85,16 +152,23 @@ func (comp *Computer) PopInput() (int, bool) {
 		if err != nil {
 			return 0, false
 		}
-	} else if comp.inpos >= len(comp.inputs) {
+	}
+	if comp.inpos >= len(comp.inputs) {
 		return 0, false
 	}
 	input := prog.Signed(comp.inputs[comp.inpos], 1e4)
+	if comp.tracing != nil {
+		comp.tracing.Inputs = append(comp.tracing.Inputs, comp.inputs[comp.inpos])
+	}
 	comp.inpos++
 	return input, true
 }
 
 func (comp *Computer) PushOutput(value int) {
 	comp.outputs = append(comp.outputs, prog.Complement(value, 1e4))
+	if comp.tracing != nil {
+		comp.tracing.Outputs = append(comp.tracing.Outputs, prog.Complement(value, 1e4))
+	}
 }
 
 func (comp *Computer) LookupSymbol(sym string) (address uint, found bool) {
@@ -102,19 +176,19 @@ func (comp *Computer) LookupSymbol(sym string) (address uint, found bool) {
 	return
 }
 
+// Labels of loaded program
+func (comp *Computer) Labels() prog.SymbolTable {
+	return comp.labels
+}
+
 func (comp *Computer) Outputs() []uint {
 	return comp.outputs
 }
 
-func NewComputer(program *prog.Program) *Computer {
-	if len(program.Instructions) > 99 {
-		panic("programs cannot be longer than 99 instructions")
-	}
-
+func NewComputer(program *prog.Program) (*Computer, error) {
 	var comp Computer
-	comp.LoadProgram(program)
-
-	return &comp
+	err := comp.LoadProgram(program)
+	return &comp, err
 }
 
 func NewComputerFile(filepath string) (*Computer, error) {
@@ -126,22 +200,57 @@ func NewComputerFile(filepath string) (*Computer, error) {
 // Doesn't erase installed program of set input but resets everything so
 // program can be run over again and give same result
 func (comp *Computer) Reset() {
-	comp.pc = 0
+	comp.pc = comp.entry
 	comp.inpos = 0
 	comp.outputs = make([]uint, 0)
 	comp.instCount = 0
+	comp.cycles = 0
+	comp.ioReads = 0
+	comp.interrupts = interruptState{}
 	for i := range comp.registers {
 		comp.registers[i] = 0
 	}
+	comp.resetDevices()
+	comp.ClearHistory()
+}
+
+// Reload is like Reset, but also puts memory back the way it was when the
+// program or snapshot was loaded, undoing any changes made by running it
+func (comp *Computer) Reload() {
+	if comp.image != nil {
+		comp.memory = *comp.image
+	}
+	comp.Reset()
+}
+
+// Address where execution of loaded program starts
+func (comp *Computer) Entry() uint {
+	return comp.entry
 }
 
-func (comp *Computer) LoadProgram(program *prog.Program) {
+// Put program into memory starting at address 0. DAT directives in program
+// are remembered so that trying to execute them can be reported
+func (comp *Computer) LoadProgram(program *prog.Program) error {
+	if len(program.Instructions) > MemorySize {
+		return fmt.Errorf("program with %d instructions does not fit in memory of %d words", len(program.Instructions), MemorySize)
+	}
+
 	comp.labels = program.Labels
+	comp.entry = program.Entry
+	comp.pc = program.Entry
+	comp.data = make(map[uint]bool)
+	comp.ClearHistory()
 	memory := comp.memory[:]
 	for i, inst := range program.Instructions {
 		machinecode := inst.MachineCode()
 		memory[i] = machinecode
+		if inst.PseudoCode() == prog.DAT {
+			comp.data[uint(i)] = true
+		}
 	}
+	image := comp.memory
+	comp.image = &image
+	return nil
 }
 
 func (comp *Computer) LoadFile(filepath string) error {
@@ -155,18 +264,20 @@ func (comp *Computer) LoadFile(filepath string) error {
 		return comp.LoadSourceCode(file)
 	} else if strings.HasSuffix(filepath, ".machine") {
 		return comp.LoadMachineCode(file)
+	} else if strings.HasSuffix(filepath, ".json") {
+		return comp.LoadSnapshot(file)
 	}
 	return fmt.Errorf("unknown file suffix")
 }
 
-// Load program into computer from reader
+// Load program into computer from reader. Since machine code doesn't say which
+// words are data, every word may be executed
 func (comp *Computer) LoadMachineCode(reader io.Reader) error {
-	program, err := disasm.Disassemble(reader)
+	program, err := disasm.DisassembleWithOptions(reader, &disasm.Options{AllCode: true})
 	if err != nil {
 		return err
 	}
-	comp.LoadProgram(program)
-	return nil
+	return comp.LoadProgram(program)
 }
 
 func (comp *Computer) LoadSourceCode(reader io.ReadSeeker) error {
@@ -174,8 +285,7 @@ func (comp *Computer) LoadSourceCode(reader io.ReadSeeker) error {
 	if err != nil {
 		return err
 	}
-	comp.LoadProgram(program)
-	return nil
+	return comp.LoadProgram(program)
 }
 
 func (comp *Computer) SetInputs(elements []uint) {
@@ -244,67 +354,95 @@ func (comp *Computer) StringInputs(s string) error {
 	return comp.LoadInputs(buffer)
 }
 
+// Instruction at program counter, or nil if program counter is outside of memory
 func (comp *Computer) Instruction() prog.Instruction {
 	pc := comp.pc
+	if pc >= MemorySize {
+		return nil
+	}
 	machinecode := comp.memory[pc]
 	instruction := disasm.DisassembleInstruction(machinecode)
 	return instruction
 }
 
-func (comp *Computer) StepChannel(out chan<- prog.AddressInstruction) bool {
-	pc := comp.pc
-
-	inst := comp.Instruction()
-
-	out <- prog.AddressInstruction{
-		Addr: pc,
-		Inst: inst,
-	}
-	// Check if we have reached a terminating instruction
-	if !inst.Run(comp) {
-		return false
-	}
-	comp.instCount++
-
-	// Make sure we didn't execute a branch instruction before updating Program counter
-	if pc == comp.pc {
-		comp.pc += 1
+func (comp *Computer) StepChannel(out chan<- prog.AddressInstruction) Result {
+	if inst := comp.Instruction(); inst != nil {
+		out <- prog.AddressInstruction{
+			Addr: comp.pc,
+			Inst: inst,
+		}
 	}
-	return true
+	return comp.Step()
 }
 
-func (comp *Computer) RunChannel(nsteps int, out chan<- prog.AddressInstruction) {
-
-	for i := 0; i < nsteps; i++ {
-		if !comp.StepChannel(out) {
-			break
-		}
-	}
+// Execute at most nsteps instructions, sending each to out
+func (comp *Computer) RunChannel(nsteps int, out chan<- prog.AddressInstruction) Result {
+	return comp.RunLimited(Limits{MaxSteps: nsteps}, out)
 }
 
-func (comp *Computer) Step() bool {
+// Execute instruction at program counter. Result tells why the computer
+// stopped, unless its reason is Running. Faults are also stored in Err
+func (comp *Computer) Step() Result {
 	pc := comp.pc
 	inst := comp.Instruction()
+	result := Result{Reason: Running, PC: pc, Instruction: inst}
+
+	switch {
+	case inst == nil:
+		result.Reason = PCOutOfRange
+	case comp.data[pc]:
+		result.Instruction = disasm.DisassembleData(comp.memory[pc])
+		result.Reason = ExecutedData
+	default:
+		comp.stalls = 0
+		comp.beginUndo()
+		comp.beginTrace(inst)
+		running := comp.Execute(inst)
+
+		// Check if we have reached a terminating instruction
+		if comp.stopped {
+			result.Reason = InputExhausted
+			break
+		}
+		if !running {
+			result.Reason = Halted
+			break
+		}
+		comp.instCount++
 
-	// Check if we have reached a terminating instruction
-	if !inst.Run(comp) {
-		return false
+		// Make sure we didn't execute a branch instruction before updating Program counter
+		taken := pc != comp.pc
+		if !taken {
+			comp.pc += 1
+		}
+		cycles := comp.costModel().Cost(inst, taken) + comp.stalls
+		comp.cycles += cycles
+		if comp.tracing != nil {
+			comp.tracing.Cycles = cycles
+		}
 	}
-	comp.instCount++
 
-	// Make sure we didn't execute a branch instruction before updating Program counter
-	if pc == comp.pc {
-		comp.pc += 1
+	comp.endTrace()
+	if result.Reason == Running {
+		comp.handleInterrupts()
 	}
-	return true
+	comp.endUndo(result.Reason == Running)
+	if result.Fault() {
+		comp.Err = result.Err()
+	}
+	return result
 }
 
-func (comp *Computer) Run(nsteps int) {
-	for i := 0; i < nsteps; i++ {
-		if !comp.Step() {
-			break
-		}
-	}
+// Execute at most nsteps instructions, returning why execution stopped
+func (comp *Computer) Run(nsteps int) Result {
+	return comp.RunLimited(Limits{MaxSteps: nsteps}, nil)
+}
+
+// Stop running program for reason, which is a fault
+func (comp *Computer) stop(reason HaltReason) Result {
+	result := Result{Reason: reason, PC: comp.pc, Instruction: comp.Instruction()}
+	comp.Err = result.Err()
+	return result
 }
 
 func (comp *Computer) PrintRegs(writer io.Writer, indices ...uint) {
@@ -328,7 +466,9 @@ func (comp *Computer) PrintProgramCounterAndSteps(writer io.Writer) {
 	fmt.Fprint(writer, "PC: ")
 	prog.NumberColor.Fprintf(writer, "%02d    ", comp.pc)
 	fmt.Fprintf(writer, "Steps: ")
-	prog.NumberColor.Fprintf(writer, "%d   \n", comp.instCount)
+	prog.NumberColor.Fprintf(writer, "%d   ", comp.instCount)
+	fmt.Fprintf(writer, "Cycles: ")
+	prog.NumberColor.Fprintf(writer, "%d   \n", comp.cycles)
 }
 
 func (comp *Computer) PrintInputs(writer io.Writer) {
@@ -356,6 +496,7 @@ func (comp *Computer) PrintOutputs(writer io.Writer) {
 func (comp *Computer) Print(writer io.Writer) {
 
 	comp.PrintProgramCounterAndSteps(writer)
+	comp.PrintInterrupts(writer)
 	fmt.Fprintln(writer)
 	comp.PrintRegs(writer, 1, 4, 7)
 	comp.PrintRegs(writer, 2, 5, 8)
@@ -2,9 +2,15 @@ package sim
 
 import (
 	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ordovician/calcutron/asm"
 	"github.com/ordovician/calcutron/prog"
@@ -81,7 +87,10 @@ func TestCounter(t *testing.T) {
 		return
 	}
 
-	comp := NewComputer(program)
+	comp, err := NewComputer(program)
+	if err != nil {
+		t.Fatal(err)
+	}
 	comp.Run(20)
 
 	data := []RegisterValues{
@@ -210,3 +219,569 @@ func TestSimpleMult(t *testing.T) {
 	// Inputs:  2, 3, 8, 4
 	// Outputs: 6, 32
 }
+
+func TestEntryPoint(t *testing.T) {
+	machinecode := `#version 1
+#entry 2
+#symbol start 2
+6109 // LODI x1, 9 is skipped
+0000
+6205 // LODI x2, 5
+0000`
+
+	var comp Computer
+	if err := comp.LoadMachineCode(bytes.NewReader([]byte(machinecode))); err != nil {
+		t.Fatalf("unable to load machine code because %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		comp.Run(10)
+		if comp.Register(1) != 0 || comp.Register(2) != 5 {
+			t.Errorf("expected execution to start at entry, but x1 = %d and x2 = %d", comp.Register(1), comp.Register(2))
+		}
+		if addr, ok := comp.LookupSymbol("start"); !ok || addr != comp.Entry() {
+			t.Errorf("expected symbol start at entry point %d", comp.Entry())
+		}
+		comp.Reset()
+	}
+}
+
+func TestHaltReasons(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		reason HaltReason
+		err    error
+		pc     uint
+	}{
+		{"halt", "INC x1\nHLT", Halted, ErrProgramHalt, 1},
+		{"input", "INP x1\nOUT x1\nBRA -2", InputExhausted, ErrAllInputRead, 0},
+		{"endless loop", "loop: INC x1\nBRA loop", StepLimit, ErrStepLimit, 0},
+		{"negative jump", "LODI x1, -10\nJMP x1", PCOutOfRange, ErrPCOutOfRange, ^uint(0) - 9},
+		{"data", "INC x1\ndata: DAT 42", ExecutedData, ErrExecuteData, 1},
+	}
+
+	for _, test := range tests {
+		program, err := asm.Assemble(bytes.NewReader([]byte(test.source)))
+		if err != nil {
+			t.Fatalf("%s: failed to assemble because %v", test.name, err)
+		}
+		comp, err := NewComputer(program)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		comp.SetInputs([]uint{1, 2})
+
+		result := comp.Run(20)
+		if result.Reason != test.reason {
+			t.Errorf("%s: expected to stop because %v, but got %v", test.name, test.reason, result.Reason)
+		}
+		if !errors.Is(result.Err(), test.err) {
+			t.Errorf("%s: expected error %v, but got %v", test.name, test.err, result.Err())
+		}
+		if result.PC != test.pc {
+			t.Errorf("%s: expected to stop at address %d, but stopped at %d", test.name, test.pc, result.PC)
+		}
+		if result.Fault() != (comp.Err != nil) {
+			t.Errorf("%s: only faults should be stored in Err, got %v", test.name, comp.Err)
+		}
+	}
+}
+
+func TestProgramTooLong(t *testing.T) {
+	var program prog.Program
+	for i := 0; i <= MemorySize; i++ {
+		program.Add(prog.NewInstruction(prog.NOP))
+	}
+	if _, err := NewComputer(&program); err == nil {
+		t.Errorf("expected program of %d instructions to not fit in memory", len(program.Instructions))
+	}
+}
+
+func TestLastMemoryAddress(t *testing.T) {
+	var comp Computer
+	comp.SetMemory(MemorySize-1, 42)
+	if comp.Memory(MemorySize-1) != 42 {
+		t.Errorf("expected last memory word to hold 42, got %d", comp.Memory(MemorySize-1))
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		limits    Limits
+		reason    HaltReason
+		loopSteps uint
+	}{
+		{"unchanged state", "INP x1\nloop: INC x2\nDEC x2\nBRA loop", Limits{DetectLoops: true}, InfiniteLoop, 3},
+		{"counting", "loop: INC x2\nBRA loop", Limits{MaxSteps: 100, DetectLoops: true}, StepLimit, 0},
+		{"time", "loop: INC x2\nBRA loop", Limits{Timeout: time.Millisecond}, Timeout, 0},
+		{"countdown", "LODI x1, 30\nloop: DEC x1\nBGT x1, x0, loop\nHLT", Limits{DetectLoops: true}, Halted, 0},
+	}
+
+	for _, test := range tests {
+		program, err := asm.Assemble(bytes.NewReader([]byte(test.source)))
+		if err != nil {
+			t.Fatalf("%s: failed to assemble because %v", test.name, err)
+		}
+		comp, _ := NewComputer(program)
+		comp.SetInputs([]uint{1})
+
+		result := comp.RunLimited(test.limits, nil)
+		if result.Reason != test.reason {
+			t.Errorf("%s: expected to stop because %v, but got %v", test.name, test.reason, result.Err())
+		}
+		if result.LoopSteps != test.loopSteps {
+			t.Errorf("%s: expected loop of %d steps, got %d", test.name, test.loopSteps, result.LoopSteps)
+		}
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	comp, err := NewComputerFile("../examples/simplemult.ct33")
+	if err != nil {
+		t.Fatalf("failed to load program because %v", err)
+	}
+	comp.SetInputs([]uint{2, 3, 8, 4})
+	comp.Run(10)
+
+	var buffer bytes.Buffer
+	if err := comp.Snapshot().Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := ReadSnapshot(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var restored Computer
+	if err := restored.Restore(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(comp.Snapshot(), restored.Snapshot()) {
+		t.Errorf("restored computer differs from original\n%v\n%v", comp.Snapshot(), restored.Snapshot())
+	}
+
+	comp.Run(50)
+	restored.Run(50)
+	if !slices.Equal(comp.Outputs(), restored.Outputs()) || comp.PC() != restored.PC() {
+		t.Errorf("expected restored computer to continue like original, got outputs %v and %v", comp.Outputs(), restored.Outputs())
+	}
+}
+
+func TestRestoreInvalidSnapshot(t *testing.T) {
+	var comp Computer
+	for _, snapshot := range []Snapshot{
+		{Version: SnapshotVersion + 1},
+		{Version: SnapshotVersion, Memory: map[uint]uint{MemorySize: 1}},
+		{Version: SnapshotVersion, Memory: map[uint]uint{3: 10000}},
+		{Version: SnapshotVersion, Inputs: []uint{1}, InputPosition: 2},
+		{Version: SnapshotVersion, Interrupts: InterruptSnapshot{Vector: MemorySize}},
+	} {
+		if err := comp.Restore(&snapshot); err == nil {
+			t.Errorf("expected error restoring %v", snapshot)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	comp, err := NewComputerFile("../examples/memadder.ct33")
+	if err != nil {
+		t.Fatalf("failed to load program because %v", err)
+	}
+	result, _ := comp.LookupSymbol("result")
+
+	comp.Run(10)
+	if comp.Memory(result) != 65 {
+		t.Fatalf("expected memadder to store 65 at result, got %d", comp.Memory(result))
+	}
+
+	comp.Reset()
+	if comp.Memory(result) != 65 {
+		t.Errorf("expected Reset to keep memory, got %d", comp.Memory(result))
+	}
+	comp.Reload()
+	if comp.Memory(result) != 0 || comp.PC() != comp.Entry() {
+		t.Errorf("expected Reload to restore memory of loaded program, got %d", comp.Memory(result))
+	}
+}
+
+func TestStepBack(t *testing.T) {
+	comp, err := NewComputerFile("../examples/reverser.ct33")
+	if err != nil {
+		t.Fatalf("failed to load program because %v", err)
+	}
+	comp.HistorySize = 100
+	comp.SetInputs([]uint{3, 7, 1, 5})
+
+	snapshots := []*Snapshot{comp.Snapshot()}
+	for comp.Step().Reason == Running {
+		snapshots = append(snapshots, comp.Snapshot())
+	}
+	if !slices.Equal(comp.Outputs(), []uint{5, 1, 7}) {
+		t.Fatalf("expected reverser to output 5, 1, 7 got %v", comp.Outputs())
+	}
+
+	for i := len(snapshots) - 2; i >= 0; i-- {
+		if !comp.StepBack() {
+			t.Fatalf("unable to step back to step %d", i)
+		}
+		if !reflect.DeepEqual(comp.Snapshot(), snapshots[i]) {
+			t.Fatalf("stepping back to step %d gave different state\n%v\n%v", i, comp.Snapshot(), snapshots[i])
+		}
+	}
+	if comp.StepBack() {
+		t.Errorf("expected nothing to undo at start of program")
+	}
+}
+
+func TestStepBackSelfJump(t *testing.T) {
+	program, err := asm.Assemble(strings.NewReader("LODI x2, -20\nloop: JMP x0, loop\nLOAD x1, x2\nHLT"))
+	if err != nil {
+		t.Fatalf("failed to assemble because %v", err)
+	}
+	comp, _ := NewComputer(program)
+	comp.HistorySize = 10
+	comp.AttachDevice(MemorySize-20, &latch{words: make([]uint, 1)})
+
+	// a jump to itself counts as not taken, so it is a step even though it
+	// changes nothing but the program counter
+	for comp.Step().Reason == Running {
+		if uint(comp.HistoryLen()) != comp.instCount {
+			t.Fatalf("expected every one of %d steps in history, got %d", comp.instCount, comp.HistoryLen())
+		}
+	}
+
+	// reading 0 from the latch stops the program, which is undone without
+	// being counted as a step
+	if !comp.StepBack() || comp.instCount != 2 || comp.PC() != 2 {
+		t.Fatalf("expected undoing stopped LOAD to leave 2 steps at address 2, got %d steps at %d", comp.instCount, comp.PC())
+	}
+	for i := 1; i >= 0; i-- {
+		if !comp.StepBack() || comp.instCount != uint(i) || comp.PC() != uint(i) {
+			t.Fatalf("expected stepping back to leave %d steps at address %d, got %d steps at %d", i, i, comp.instCount, comp.PC())
+		}
+	}
+	if comp.StepBack() {
+		t.Errorf("expected nothing to undo at start of program")
+	}
+}
+
+func TestHistorySize(t *testing.T) {
+	comp, _ := NewComputerFile("../examples/outputter.ct33")
+	comp.HistorySize = 5
+	comp.Run(25)
+
+	if comp.HistoryLen() != 5 {
+		t.Errorf("expected history of 5 instructions, got %d", comp.HistoryLen())
+	}
+	for i := 0; i < 5; i++ {
+		comp.StepBack()
+	}
+	if comp.StepBack() {
+		t.Errorf("expected to only step back 5 instructions")
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	comp, _ := NewComputerFile("../examples/outputter.ct33")
+	comp.HistorySize = 100
+	decrement, _ := comp.LookupSymbol("decrement")
+	comp.SetBreakpoint(decrement)
+
+	for i := 0; i < 3; i++ {
+		result := comp.RunLimited(DefaultLimits, nil)
+		if result.Reason != Breakpoint || result.PC != decrement || len(comp.Outputs()) != i {
+			t.Fatalf("expected to stop at breakpoint %d after %d outputs, got %v and outputs %v", decrement, i, result.Err(), comp.Outputs())
+		}
+	}
+
+	comp.Step()
+	if steps := comp.ReverseContinue(); steps != 1 || comp.PC() != decrement {
+		t.Errorf("expected to step back 1 instruction to breakpoint, stepped back %d to %d", steps, comp.PC())
+	}
+	if steps := comp.ReverseContinue(); steps != 3 || len(comp.Outputs()) != 1 {
+		t.Errorf("expected to step back 3 instructions to previous breakpoint, stepped back %d with outputs %v", steps, comp.Outputs())
+	}
+
+	comp.ClearBreakpoint(decrement)
+	if result := comp.RunLimited(DefaultLimits, nil); result.Reason != Halted {
+		t.Errorf("expected program to run to end without breakpoints, got %v", result.Err())
+	}
+}
+
+func TestTrace(t *testing.T) {
+	comp, _ := NewComputerFile("../examples/memadder.ct33")
+	var buffer bytes.Buffer
+	tracer := NewJSONTracer(&buffer)
+	comp.Tracer = tracer
+	comp.Run(100)
+	if err := tracer.Flush(); err != nil {
+		t.Fatalf("failed to write trace because %v", err)
+	}
+
+	var records []TraceRecord
+	decoder := json.NewDecoder(&buffer)
+	for decoder.More() {
+		var record TraceRecord
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("failed to read trace because %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 5 {
+		t.Fatalf("expected trace of 5 instructions, got %d", len(records))
+	}
+
+	add := records[2]
+	expected := TraceRecord{
+		Step:   3,
+		PC:     2,
+		NextPC: 3,
+		Word:   1312,
+		Source: "ADD x3, x1, x2",
+		Cycles: 1,
+		Reads:  []RegisterRead{{1, 42}, {2, 23}},
+		Writes: []RegisterWrite{{3, 0, 65}},
+	}
+	if !reflect.DeepEqual(add, expected) {
+		t.Errorf("expected %v, got %v", expected, add)
+	}
+	if store := records[3]; !slices.Equal(store.MemoryWrites, []MemoryWrite{{7, 0, 65}}) {
+		t.Errorf("expected STOR to write 65 to address 7, got %v", store.MemoryWrites)
+	}
+	if halt := records[4]; halt.Source != "HLT" || halt.NextPC != halt.PC {
+		t.Errorf("expected program to halt, got %v", halt)
+	}
+}
+
+func TestCostModel(t *testing.T) {
+	model, err := ReadCostModel(strings.NewReader(`{"opcodes": {"load": 5, "INP": 2}, "taken_branch": 2}`))
+	if err != nil {
+		t.Fatalf("failed to read cost model because %v", err)
+	}
+
+	comp, _ := NewComputerFile("../examples/simplemult.ct33")
+	comp.Costs = model
+	comp.HistorySize = 10
+	comp.SetInputs([]uint{3, 4})
+	comp.Run(100)
+
+	// INP and CLR take 5 cycles, the loop 4 * 3 plus 3 * 2 for taken branches,
+	// OUT 1 and JMP 3, while reading input which is not there is free
+	if comp.Cycles() != 27 {
+		t.Errorf("expected 27 cycles, got %d", comp.Cycles())
+	}
+	comp.StepBack()
+	if comp.Cycles() != 24 {
+		t.Errorf("expected stepping back over JMP to undo 3 cycles, got %d", comp.Cycles())
+	}
+	comp.Reset()
+	if comp.Cycles() != 0 {
+		t.Errorf("expected Reset to clear cycles, got %d", comp.Cycles())
+	}
+
+	if _, err := ReadCostModel(strings.NewReader(`{"opcodes": {"FOO": 2}}`)); err == nil {
+		t.Errorf("expected unknown instruction in cost model to be an error")
+	}
+}
+
+// Device remembering the last value written to each of its words
+type latch struct {
+	words []uint
+	reads int
+}
+
+func (l *latch) Name() string { return "latch" }
+func (l *latch) Size() uint   { return uint(len(l.words)) }
+
+func (l *latch) Read(offset uint) (uint, bool) {
+	l.reads++
+	return l.words[offset], l.words[offset] != 0
+}
+
+func (l *latch) Write(offset uint, value uint) {
+	l.words[offset] = value
+}
+
+func (l *latch) SaveState() any {
+	return slices.Clone(l.words)
+}
+
+func (l *latch) RestoreState(state any) {
+	l.words = state.([]uint)
+}
+
+func TestDevices(t *testing.T) {
+	source := "LODI x1, 42\nLODI x2, -20\nSTOR x1, x2, 1\nLOAD x3, x2, 1\nOUT x3\nLOAD x4, x2\nINC x5\nHLT"
+	program, err := asm.Assemble(bytes.NewReader([]byte(source)))
+	if err != nil {
+		t.Fatalf("failed to assemble because %v", err)
+	}
+	comp, _ := NewComputer(program)
+	dev := &latch{words: make([]uint, 2)}
+	if err := comp.AttachDevice(MemorySize-20, dev); err != nil {
+		t.Fatalf("failed to attach device because %v", err)
+	}
+
+	// reading 0 from first word of latch stops program without changing x4
+	comp.SetRegister(4, 7)
+	result := comp.Run(20)
+	if result.Reason != InputExhausted || result.PC != 5 {
+		t.Errorf("expected device to stop program at 5, got %v at %d", result.Reason, result.PC)
+	}
+	if dev.words[1] != 42 || comp.Outputs()[0] != 42 || comp.Register(4) != 7 {
+		t.Errorf("expected 42 to pass through device, got %v and outputs %v", dev.words, comp.Outputs())
+	}
+
+	// debugger can look at memory without reading from devices
+	comp.Memory(MemorySize - 19)
+	if dev.reads != 2 {
+		t.Errorf("expected only instructions to read device, got %d reads", dev.reads)
+	}
+
+	overlapping := []uint{MemorySize - 21, MemorySize - 19, MemorySize - 1, MemorySize}
+	for _, addr := range overlapping {
+		if err := comp.AttachDevice(addr, &latch{words: make([]uint, 2)}); err == nil {
+			t.Errorf("expected device at %d to overlap another device or be outside memory", addr)
+		}
+	}
+}
+
+func TestTraceDevices(t *testing.T) {
+	source := "LODI x1, 42\nLODI x2, -20\nSTOR x1, x2, 1\nLOAD x3, x2, 1\nOUT x3\nHLT"
+	program, err := asm.Assemble(bytes.NewReader([]byte(source)))
+	if err != nil {
+		t.Fatalf("failed to assemble because %v", err)
+	}
+	comp, _ := NewComputer(program)
+	if err := comp.AttachDevice(MemorySize-20, &latch{words: make([]uint, 2)}); err != nil {
+		t.Fatalf("failed to attach device because %v", err)
+	}
+	var buffer bytes.Buffer
+	tracer := NewCSVTracer(&buffer)
+	comp.Tracer = tracer
+	comp.Run(10)
+	if err := tracer.Flush(); err != nil {
+		t.Fatalf("failed to write trace because %v", err)
+	}
+
+	rows, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil || len(rows) != 7 {
+		t.Fatalf("expected header and 6 rows of trace, got %d rows and error %v", len(rows), err)
+	}
+	column := make(map[string]int)
+	for i, name := range rows[0] {
+		column[name] = i
+	}
+	if write := rows[3][column["device_writes"]]; write != "latch@9981=0042" {
+		t.Errorf("expected STOR to write 42 to latch, got '%s'", write)
+	}
+	if read := rows[4][column["device_reads"]]; read != "latch@9981=0042" {
+		t.Errorf("expected LOAD to read 42 from latch, got '%s'", read)
+	}
+	if output := rows[5]; output[column["device_writes"]] != "" || output[column["outputs"]] != "42" {
+		t.Errorf("expected tape to be traced as output, got %v", output)
+	}
+	if word := rows[6][column["word"]]; word != "0000" {
+		t.Errorf("expected HLT as 4-digit word 0000, got '%s'", word)
+	}
+}
+
+func TestInterrupts(t *testing.T) {
+	source := "LODI x9, -10\nLODI x2, handler\nSTOR x2, x9, 1\nLODI x2, 1\nSTOR x2, x9\nloop: INC x3\nBRA loop\nhandler: LOAD x1, x9, 2\nOUT x1\nHLT"
+	program, err := asm.Assemble(bytes.NewReader([]byte(source)))
+	if err != nil {
+		t.Fatalf("failed to assemble because %v", err)
+	}
+	comp, _ := NewComputer(program)
+	comp.HistorySize = 10
+	if err := comp.AttachDevice(MemorySize-10, NewInterruptController(comp)); err != nil {
+		t.Fatalf("failed to attach interrupt controller because %v", err)
+	}
+	button := comp.AddInterruptSource("button")
+	comp.RaiseInterrupt(button)
+	if status := comp.String(); !strings.Contains(status, "Pending: button") {
+		t.Errorf("expected status to show pending interrupt, got\n%s", status)
+	}
+
+	// interrupts are enabled only after the instruction following the one enabling them
+	for i := 0; i < 5; i++ {
+		comp.Step()
+	}
+	if comp.InterruptsEnabled() || comp.PC() != 5 {
+		t.Errorf("expected interrupts not to be enabled yet at 5, got PC %d", comp.PC())
+	}
+	comp.Step()
+	if comp.PC() != comp.InterruptVector() || comp.Register(InterruptRegister) != 6 {
+		t.Errorf("expected to jump to handler at %d from 6, got PC %d and x8 %d",
+			comp.InterruptVector(), comp.PC(), comp.Register(InterruptRegister))
+	}
+	if comp.InterruptsEnabled() || len(comp.PendingInterrupts()) != 0 {
+		t.Errorf("expected interrupts to be disabled and nothing pending in handler")
+	}
+
+	// stepping back undoes taking the interrupt
+	comp.StepBack()
+	if comp.PC() != 5 || comp.Register(InterruptRegister) != 0 || len(comp.PendingInterrupts()) != 1 {
+		t.Errorf("expected interrupt to be pending again before executing 5, got PC %d", comp.PC())
+	}
+
+	comp.Run(10)
+	if outputs := comp.Outputs(); len(outputs) != 1 || outputs[0] != uint(button) {
+		t.Errorf("expected handler to output cause %d, got %v", button, outputs)
+	}
+}
+
+func TestSnapshotInterrupts(t *testing.T) {
+	source := "LODI x9, -10\nLODI x2, handler\nSTOR x2, x9, 1\nLODI x2, 1\nSTOR x2, x9\nloop: INC x3\nBRA loop\nhandler: LOAD x1, x9, 2\nOUT x1\nHLT"
+	program, err := asm.Assemble(bytes.NewReader([]byte(source)))
+	if err != nil {
+		t.Fatalf("failed to assemble because %v", err)
+	}
+	comp, _ := NewComputer(program)
+	if err := comp.AttachDevice(MemorySize-10, NewInterruptController(comp)); err != nil {
+		t.Fatalf("failed to attach interrupt controller because %v", err)
+	}
+	comp.AddInterruptSource("button")
+	comp.AddInterruptSource("bell")
+	comp.Run(7)
+	comp.RaiseInterrupt(2)
+	if !comp.InterruptsEnabled() {
+		t.Fatalf("expected interrupts to be enabled after 7 steps")
+	}
+
+	var buffer bytes.Buffer
+	if err := comp.Snapshot().Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := ReadSnapshot(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// restoring replaces interrupt state the computer had before
+	restored, _ := NewComputer(program)
+	if err := restored.AttachDevice(MemorySize-10, NewInterruptController(restored)); err != nil {
+		t.Fatalf("failed to attach interrupt controller because %v", err)
+	}
+	restored.AddInterruptSource("button")
+	restored.AddInterruptSource("bell")
+	restored.RaiseInterrupt(1)
+	if err := restored.Restore(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(comp.Snapshot(), restored.Snapshot()) {
+		t.Errorf("restored computer differs from original\n%v\n%v", comp.Snapshot(), restored.Snapshot())
+	}
+	if pending := restored.PendingInterrupts(); !slices.Equal(pending, []string{"bell"}) {
+		t.Errorf("expected only bell to be pending, got %v", pending)
+	}
+
+	comp.Run(10)
+	restored.Run(10)
+	if !slices.Equal(restored.Outputs(), []uint{2}) || !slices.Equal(comp.Outputs(), restored.Outputs()) {
+		t.Errorf("expected both computers to handle bell interrupt, got outputs %v and %v", comp.Outputs(), restored.Outputs())
+	}
+}
@@ -0,0 +1,203 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ordovician/calcutron/prog"
+	"golang.org/x/exp/slices"
+)
+
+// Version of JSON format written for snapshots
+const SnapshotVersion = 2
+
+// Snapshot holds the complete state of a computer, so that it can be restored
+// later or saved to a file. Saved as JSON it looks like:
+//
+//	{
+//	  "version": 2,
+//	  "pc": 4,
+//	  "entry": 0,
+//	  "registers": [0, 3, 0, 7, 0, 0, 0, 0, 0, 0],
+//	  "memory": {"0": 5109, "1": 5209, "2": 1312},
+//	  "data": [8],
+//	  "inputs": [3, 4],
+//	  "input_position": 2,
+//	  "outputs": [7],
+//	  "steps": 4,
+//	  "cycles": 6,
+//	  "labels": {"loop": 0},
+//	  "interrupts": {"enabled": true, "enable_in": 0, "vector": 20, "cause": 1, "pending": 2}
+//	}
+//
+// Registers and words of memory hold 4-digit machine words, with negative
+// numbers stored as ten's complement. Only memory words which are not zero
+// are included, keyed by their address. Data lists addresses of DAT
+// directives, which cannot be executed. Pending interrupts are a bit mask
+// with bit n-1 set when interrupt n has been raised. Devices keep their own
+// state, which is not part of the snapshot.
+type Snapshot struct {
+	Version       int               `json:"version"`
+	PC            uint              `json:"pc"`
+	Entry         uint              `json:"entry"`
+	Registers     [10]uint          `json:"registers"`
+	Memory        map[uint]uint     `json:"memory"`
+	Data          []uint            `json:"data"`
+	Inputs        []uint            `json:"inputs"`
+	InputPosition int               `json:"input_position"`
+	Outputs       []uint            `json:"outputs"`
+	Steps         uint              `json:"steps"`
+	Cycles        uint              `json:"cycles"`
+	Labels        prog.SymbolTable  `json:"labels"`
+	Interrupts    InterruptSnapshot `json:"interrupts"`
+}
+
+// State of interrupts held by snapshot
+type InterruptSnapshot struct {
+	Enabled  bool   `json:"enabled"`
+	EnableIn int    `json:"enable_in"` // instructions left before interrupts are enabled
+	Vector   uint   `json:"vector"`
+	Cause    uint   `json:"cause"`
+	Pending  uint64 `json:"pending"`
+}
+
+// Take snapshot of current state of computer
+func (comp *Computer) Snapshot() *Snapshot {
+	snapshot := Snapshot{
+		Version:       SnapshotVersion,
+		PC:            comp.pc,
+		Entry:         comp.entry,
+		Registers:     comp.registers,
+		Memory:        make(map[uint]uint),
+		Data:          make([]uint, 0, len(comp.data)),
+		Inputs:        append([]uint{}, comp.inputs...),
+		InputPosition: comp.inpos,
+		Outputs:       append([]uint{}, comp.outputs...),
+		Steps:         comp.instCount,
+		Cycles:        comp.cycles,
+		Labels:        make(prog.SymbolTable),
+		Interrupts: InterruptSnapshot{
+			Enabled:  comp.interrupts.enabled,
+			EnableIn: comp.interrupts.enableIn,
+			Vector:   comp.interrupts.vector,
+			Cause:    comp.interrupts.cause,
+			Pending:  comp.interrupts.pending,
+		},
+	}
+
+	for addr, word := range comp.memory {
+		if word != 0 {
+			snapshot.Memory[uint(addr)] = word
+		}
+	}
+	for addr := range comp.data {
+		snapshot.Data = append(snapshot.Data, addr)
+	}
+	slices.Sort(snapshot.Data)
+	for label, addr := range comp.labels {
+		snapshot.Labels[label] = addr
+	}
+	return &snapshot
+}
+
+// Restore state of computer from snapshot. Computer is left unchanged if the
+// snapshot is not valid
+func (comp *Computer) Restore(snapshot *Snapshot) error {
+	if snapshot.Version != SnapshotVersion {
+		return fmt.Errorf("snapshot version %d is not supported, only version %d", snapshot.Version, SnapshotVersion)
+	}
+	if snapshot.InputPosition < 0 || snapshot.InputPosition > len(snapshot.Inputs) {
+		return fmt.Errorf("snapshot input position %d is outside of %d inputs", snapshot.InputPosition, len(snapshot.Inputs))
+	}
+	for i, reg := range snapshot.Registers {
+		if reg >= 1e4 {
+			return fmt.Errorf("snapshot register x%d holds %d which is not a 4-digit word", i, reg)
+		}
+	}
+	for addr, word := range snapshot.Memory {
+		if addr >= MemorySize || word >= 1e4 {
+			return fmt.Errorf("snapshot memory at address %d holds %d, but memory has %d 4-digit words", addr, word, MemorySize)
+		}
+	}
+
+	if interrupts := snapshot.Interrupts; interrupts.EnableIn < 0 || interrupts.EnableIn > 2 || interrupts.Vector >= MemorySize {
+		return fmt.Errorf("snapshot interrupt state is not valid, enabling in %d with vector %d", interrupts.EnableIn, interrupts.Vector)
+	}
+
+	comp.pc = snapshot.PC
+	comp.entry = snapshot.Entry
+	comp.registers = snapshot.Registers
+	comp.registers[0] = 0
+	comp.memory = [MemorySize]uint{}
+	for addr, word := range snapshot.Memory {
+		comp.memory[addr] = word
+	}
+	comp.data = make(map[uint]bool)
+	for _, addr := range snapshot.Data {
+		comp.data[addr] = true
+	}
+	comp.inputs = slices.Clone(snapshot.Inputs)
+	comp.inpos = snapshot.InputPosition
+	comp.outputs = slices.Clone(snapshot.Outputs)
+	comp.instCount = snapshot.Steps
+	comp.cycles = snapshot.Cycles
+	comp.interrupts = interruptState{
+		enabled:  snapshot.Interrupts.Enabled,
+		enableIn: snapshot.Interrupts.EnableIn,
+		vector:   snapshot.Interrupts.Vector,
+		cause:    snapshot.Interrupts.Cause,
+		pending:  snapshot.Interrupts.Pending,
+	}
+	comp.ClearHistory()
+	comp.labels = make(prog.SymbolTable)
+	for label, addr := range snapshot.Labels {
+		comp.labels[label] = addr
+	}
+	return nil
+}
+
+// Write snapshot as indented JSON
+func (snapshot *Snapshot) Write(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// Read snapshot written as JSON by Write
+func ReadSnapshot(reader io.Reader) (*Snapshot, error) {
+	var snapshot Snapshot
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("unable to read snapshot because %w", err)
+	}
+	return &snapshot, nil
+}
+
+// Save snapshot of computer to file at filepath
+func (comp *Computer) SaveSnapshotFile(filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return fmt.Errorf("could not save snapshot: %w", err)
+	}
+	defer file.Close()
+
+	return comp.Snapshot().Write(file)
+}
+
+// Restore computer from snapshot read from reader. The restored memory is what
+// Reload goes back to
+func (comp *Computer) LoadSnapshot(reader io.Reader) error {
+	snapshot, err := ReadSnapshot(reader)
+	if err != nil {
+		return err
+	}
+	if err := comp.Restore(snapshot); err != nil {
+		return err
+	}
+	image := comp.memory
+	comp.image = &image
+	return nil
+}
@@ -0,0 +1,259 @@
+package sim
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ordovician/calcutron/prog"
+)
+
+// TraceRecord describes everything a single executed instruction did. As a
+// line of JSON it looks like:
+//
+//	{"step":3,"pc":2,"next_pc":3,"word":1312,"source":"ADD x3, x1, x2","cycles":1,
+//	 "reads":[{"reg":1,"value":4},{"reg":2,"value":9997}],
+//	 "writes":[{"reg":3,"old":0,"new":1}]}
+//
+// Step counts executed instructions starting at 1. Cycles are given by the
+// cost model of the computer, and are 0 for instructions which stop the
+// program. Those did not complete, so their NextPC is the same as PC, while
+// a completed instruction always moves the program counter, even when jumping
+// to itself. Registers are numbered 1 to 9, since x0 always holds zero. Values
+// are 4-digit machine words, with negative numbers stored as ten's
+// complement, just like in snapshots. Reads and writes are listed in the
+// order the instruction made them. Fields listing accesses are left out when
+// the instruction made none. Reading and writing devices other than the tape
+// is listed as device I/O, such as {"device":"random","addr":9990,"value":1234},
+// while the tape gives inputs and outputs.
+type TraceRecord struct {
+	Step         uint            `json:"step"`
+	PC           uint            `json:"pc"`
+	NextPC       uint            `json:"next_pc"`
+	Word         uint            `json:"word"`
+	Source       string          `json:"source"`
+	Cycles       uint            `json:"cycles"`
+	Reads        []RegisterRead  `json:"reads,omitempty"`
+	Writes       []RegisterWrite `json:"writes,omitempty"`
+	MemoryReads  []MemoryRead    `json:"memory_reads,omitempty"`
+	MemoryWrites []MemoryWrite   `json:"memory_writes,omitempty"`
+	DeviceReads  []DeviceAccess  `json:"device_reads,omitempty"`
+	DeviceWrites []DeviceAccess  `json:"device_writes,omitempty"`
+	Inputs       []uint          `json:"inputs,omitempty"`
+	Outputs      []uint          `json:"outputs,omitempty"`
+}
+
+type RegisterRead struct {
+	Register uint `json:"reg"`
+	Value    uint `json:"value"`
+}
+
+type RegisterWrite struct {
+	Register uint `json:"reg"`
+	Old      uint `json:"old"`
+	New      uint `json:"new"`
+}
+
+type MemoryRead struct {
+	Address uint `json:"addr"`
+	Value   uint `json:"value"`
+}
+
+type MemoryWrite struct {
+	Address uint `json:"addr"`
+	Old     uint `json:"old"`
+	New     uint `json:"new"`
+}
+
+type DeviceAccess struct {
+	Device  string `json:"device"`
+	Address uint   `json:"addr"`
+	Value   uint   `json:"value"`
+}
+
+// Tracer gets a record of every instruction executed by a computer
+type Tracer interface {
+	Trace(record *TraceRecord)
+}
+
+// Start recording accesses made by instruction about to be executed
+func (comp *Computer) beginTrace(inst prog.Instruction) {
+	if comp.Tracer == nil {
+		return
+	}
+	comp.tracing = &TraceRecord{
+		Step:   comp.instCount + 1,
+		PC:     comp.pc,
+		Word:   comp.memory[comp.pc],
+		Source: plainSource(inst),
+	}
+}
+
+// Pass record of executed instruction on to Tracer
+func (comp *Computer) endTrace() {
+	record := comp.tracing
+	comp.tracing = nil
+	if record == nil {
+		return
+	}
+	record.NextPC = comp.pc
+	comp.Tracer.Trace(record)
+}
+
+// Tracers passes every record on to each of its tracers
+type Tracers []Tracer
+
+func (tracers Tracers) Trace(record *TraceRecord) {
+	for _, tracer := range tracers {
+		tracer.Trace(record)
+	}
+}
+
+func (comp *Computer) traceRegisterRead(i uint) {
+	if comp.tracing != nil {
+		comp.tracing.Reads = append(comp.tracing.Reads, RegisterRead{i, comp.registers[i]})
+	}
+}
+
+func (comp *Computer) traceRegisterWrite(i uint, old uint) {
+	if comp.tracing != nil {
+		comp.tracing.Writes = append(comp.tracing.Writes, RegisterWrite{i, old, comp.registers[i]})
+	}
+}
+
+func (comp *Computer) traceMemoryRead(address uint) {
+	if comp.tracing != nil {
+		comp.tracing.MemoryReads = append(comp.tracing.MemoryReads, MemoryRead{address, comp.memory[address]})
+	}
+}
+
+func (comp *Computer) traceMemoryWrite(address uint, old uint) {
+	if comp.tracing != nil {
+		comp.tracing.MemoryWrites = append(comp.tracing.MemoryWrites, MemoryWrite{address, old, comp.memory[address]})
+	}
+}
+
+// Tape is left out, since it is traced as inputs and outputs
+func (comp *Computer) traceDeviceRead(mapped *MappedDevice, address uint, value uint) {
+	if comp.tracing != nil && mapped.Address != TapeAddress {
+		access := DeviceAccess{mapped.Device.Name(), address, value}
+		comp.tracing.DeviceReads = append(comp.tracing.DeviceReads, access)
+	}
+}
+
+func (comp *Computer) traceDeviceWrite(mapped *MappedDevice, address uint, value uint) {
+	if comp.tracing != nil && mapped.Address != TapeAddress {
+		access := DeviceAccess{mapped.Device.Name(), address, value}
+		comp.tracing.DeviceWrites = append(comp.tracing.DeviceWrites, access)
+	}
+}
+
+// JSONTracer writes each record as a line of JSON, known as JSON Lines.
+// Call Flush when done to write buffered records and check for errors
+type JSONTracer struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	err     error
+}
+
+func NewJSONTracer(writer io.Writer) *JSONTracer {
+	buffered := bufio.NewWriter(writer)
+	return &JSONTracer{writer: buffered, encoder: json.NewEncoder(buffered)}
+}
+
+func (tracer *JSONTracer) Trace(record *TraceRecord) {
+	if tracer.err == nil {
+		tracer.err = tracer.encoder.Encode(record)
+	}
+}
+
+// Write buffered records, returning the first error which occurred while tracing
+func (tracer *JSONTracer) Flush() error {
+	if tracer.err != nil {
+		return tracer.err
+	}
+	return tracer.writer.Flush()
+}
+
+// Columns of CSV written by CSVTracer
+var CSVTraceHeader = []string{"step", "pc", "next_pc", "word", "source", "reads", "writes", "memory_reads", "memory_writes", "device_reads", "device_writes", "inputs", "outputs", "cycles"}
+
+// CSVTracer writes each record as a row of comma separated values, starting
+// with a row holding CSVTraceHeader. Columns of accesses list them separated
+// by spaces, as register=value for reads, register:old->new for writes, and
+// likewise with addresses for memory. Devices are given as name@address=value
+// for both reads and writes. A trace line could look like:
+//
+//	3,2,3,1312,"ADD x3, x1, x2",x1=0004 x2=9997,x3:0000->0001,,,,,,,1
+//
+// Call Flush when done to write buffered records and check for errors.
+type CSVTracer struct {
+	writer *csv.Writer
+	header bool
+}
+
+func NewCSVTracer(writer io.Writer) *CSVTracer {
+	return &CSVTracer{writer: csv.NewWriter(writer)}
+}
+
+func (tracer *CSVTracer) Trace(record *TraceRecord) {
+	if !tracer.header {
+		tracer.writer.Write(CSVTraceHeader)
+		tracer.header = true
+	}
+
+	var reads, writes, memoryReads, memoryWrites, deviceReads, deviceWrites []string
+	for _, use := range record.Reads {
+		reads = append(reads, fmt.Sprintf("x%d=%04d", use.Register, use.Value))
+	}
+	for _, use := range record.Writes {
+		writes = append(writes, fmt.Sprintf("x%d:%04d->%04d", use.Register, use.Old, use.New))
+	}
+	for _, use := range record.MemoryReads {
+		memoryReads = append(memoryReads, fmt.Sprintf("%d=%04d", use.Address, use.Value))
+	}
+	for _, use := range record.MemoryWrites {
+		memoryWrites = append(memoryWrites, fmt.Sprintf("%d:%04d->%04d", use.Address, use.Old, use.New))
+	}
+	for _, use := range record.DeviceReads {
+		deviceReads = append(deviceReads, fmt.Sprintf("%s@%d=%04d", use.Device, use.Address, use.Value))
+	}
+	for _, use := range record.DeviceWrites {
+		deviceWrites = append(deviceWrites, fmt.Sprintf("%s@%d=%04d", use.Device, use.Address, use.Value))
+	}
+
+	tracer.writer.Write([]string{
+		strconv.Itoa(int(record.Step)),
+		strconv.Itoa(int(record.PC)),
+		strconv.Itoa(int(record.NextPC)),
+		fmt.Sprintf("%04d", record.Word),
+		record.Source,
+		strings.Join(reads, " "),
+		strings.Join(writes, " "),
+		strings.Join(memoryReads, " "),
+		strings.Join(memoryWrites, " "),
+		strings.Join(deviceReads, " "),
+		strings.Join(deviceWrites, " "),
+		joinWords(record.Inputs),
+		joinWords(record.Outputs),
+		strconv.Itoa(int(record.Cycles)),
+	})
+}
+
+// Write buffered records, returning the first error which occurred while tracing
+func (tracer *CSVTracer) Flush() error {
+	tracer.writer.Flush()
+	return tracer.writer.Error()
+}
+
+func joinWords(words []uint) string {
+	fields := make([]string, len(words))
+	for i, word := range words {
+		fields[i] = strconv.Itoa(int(word))
+	}
+	return strings.Join(fields, " ")
+}
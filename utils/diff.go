@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Number of unchanged lines shown around each change in a unified diff
+const diffContext = 3
+
+type diffOp struct {
+	kind byte // ' ' unchanged, '-' removed, '+' added
+	line string
+}
+
+// Compute the line edits turning a into b using the longest common subsequence.
+// Source files for Calcutron-33 are small so the quadratic table is fine
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// Diff returns a unified diff between the lines of a and b. An empty string
+// is returned when there are no differences
+func Diff(oldName, newName string, a, b []string) string {
+	ops := diffLines(a, b)
+
+	var builder strings.Builder
+	// oldLine and newLine are 1-based line numbers of ops[k] in a and b
+	oldLine, newLine := 1, 1
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			oldLine++
+			newLine++
+			continue
+		}
+
+		// found a change. Include context before and extend hunk until
+		// we find more than 2*diffContext unchanged lines in a row
+		start := k - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := k
+		for unchanged := 0; end < len(ops) && unchanged <= 2*diffContext; end++ {
+			if ops[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		// trim trailing context down to diffContext lines
+		for end > k && ops[end-1].kind == ' ' && end-lastChange(ops[:end]) > diffContext+1 {
+			end--
+		}
+
+		oldStart, newStart := oldLine-(k-start), newLine-(k-start)
+		oldCount, newCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+
+		if builder.Len() == 0 {
+			fmt.Fprintf(&builder, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(&builder, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&builder, "%c%s\n", op.kind, op.line)
+		}
+
+		for _, op := range ops[k:end] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		k = end
+	}
+	return builder.String()
+}
+
+// index of last changed operation in ops
+func lastChange(ops []diffOp) int {
+	for i := len(ops) - 1; i >= 0; i-- {
+		if ops[i].kind != ' ' {
+			return i
+		}
+	}
+	return -1
+}
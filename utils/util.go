@@ -172,3 +172,32 @@ func ParseSetReg(line string) (reg, value uint8, erro error) {
 
 	return
 }
+
+// Parse a list of addresses such as '3,10-12' into the addresses 3, 10, 11 and 12
+func ParseAddressRanges(s string) ([]uint, error) {
+	addresses := make([]uint, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("'%s' is not a valid address", first)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(last))
+			if err != nil || end < start {
+				return nil, fmt.Errorf("'%s' is not a valid address range", part)
+			}
+		}
+
+		for addr := start; addr <= end; addr++ {
+			addresses = append(addresses, uint(addr))
+		}
+	}
+	return addresses, nil
+}
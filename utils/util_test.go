@@ -38,3 +38,17 @@ func TestParseSetReg(t *testing.T) {
 		t.Errorf("Register index and value should be 3 and 42 respectively, but we got %d and %d", reg, value)
 	}
 }
+
+func TestParseAddressRanges(t *testing.T) {
+	addresses, err := ParseAddressRanges("3, 10-12")
+	if err != nil {
+		t.Fatalf("Could not parse '3, 10-12' because %v", err)
+	}
+	if fmt.Sprint(addresses) != "[3 10 11 12]" {
+		t.Errorf("Expected addresses [3 10 11 12] got %v", addresses)
+	}
+
+	if _, err := ParseAddressRanges("5-2"); err == nil {
+		t.Errorf("Range with end before start should not be accepted")
+	}
+}